@@ -9,9 +9,12 @@ require (
 )
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/schema v1.2.0
 	github.com/itzg/go-flagsfiller v1.9.1
+	github.com/klauspost/compress v1.17.11
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,117 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nextmv-io/sdk/run/decode"
+)
+
+type auxiliaryInputsKey struct{}
+
+// withAuxiliaryInputs returns a context that carries the auxiliary inputs of
+// the runner configuration, if it implements AuxiliaryInputer.
+func withAuxiliaryInputs(ctx context.Context, runnerConfig any) context.Context {
+	auxiliaryInputer, ok := runnerConfig.(AuxiliaryInputer)
+	if !ok || len(auxiliaryInputer.AuxiliaryInputs()) == 0 {
+		return ctx
+	}
+	return context.WithValue(
+		ctx, auxiliaryInputsKey{}, auxiliaryInputer.AuxiliaryInputs(),
+	)
+}
+
+// AuxiliaryInputs returns the names of the auxiliary inputs of a run.
+func AuxiliaryInputs(ctx context.Context) []string {
+	paths, _ := ctx.Value(auxiliaryInputsKey{}).(map[string]string)
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	return names
+}
+
+// AuxiliaryInput opens the auxiliary input with the given name, such as a
+// distance matrix that is too big to be part of the main input. Compressed
+// files are decompressed, the compression is detected by its magic bytes. The
+// caller must close the returned reader.
+func AuxiliaryInput(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := auxiliaryInputPath(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := decompress(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return readCloser{Reader: reader, closers: []io.Closer{reader, f}}, nil
+}
+
+// DecodeAuxiliaryInput decodes the auxiliary input with the given name. The
+// decoder is chosen by the file extension: .csv files are decoded as CSV,
+// .jsonl files as JSON Lines and all other files as JSON. The extension of a
+// compression, such as .gz or .zst, is ignored.
+func DecodeAuxiliaryInput[T any](ctx context.Context, name string) (value T, err error) {
+	path, err := auxiliaryInputPath(ctx, name)
+	if err != nil {
+		return value, err
+	}
+	reader, err := AuxiliaryInput(ctx, name)
+	if err != nil {
+		return value, err
+	}
+	defer func() {
+		tempErr := reader.Close()
+		// the first error is the most important
+		if err == nil {
+			err = tempErr
+		}
+	}()
+
+	var decoder decode.Decoder
+	switch filepath.Ext(trimCompressionExt(path)) {
+	case ".csv":
+		decoder = decode.CSV()
+	case ".jsonl":
+		decoder = decode.JSONL()
+	default:
+		decoder = decode.JSON()
+	}
+	if err := decoder.Decode(reader, &value); err != nil {
+		return value, fmt.Errorf("auxiliary input %q: %w", name, err)
+	}
+	return value, nil
+}
+
+func auxiliaryInputPath(ctx context.Context, name string) (string, error) {
+	paths, _ := ctx.Value(auxiliaryInputsKey{}).(map[string]string)
+	path, ok := paths[name]
+	if !ok {
+		return "", fmt.Errorf("auxiliary input %q is not configured", name)
+	}
+	return path, nil
+}
+
+// readCloser is a reader that closes all closers in order.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r readCloser) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		if tempErr := closer.Close(); err == nil {
+			err = tempErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,421 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cliRunner is the CLIRunner. Next to running the algorithm once, it runs it
+// in batch mode, once per input, if an input directory or JSON lines input is
+// configured. The IOProducer of the runner is not used in batch mode. Other
+// commands than solve do not run the algorithm, see Command.
+type cliRunner[Input, Option, Solution any] struct {
+	Runner[CLIRunnerConfig, Input, Option, Solution]
+	// envPrefix is the prefix of the environment variables of the runner.
+	envPrefix string
+}
+
+func (r *cliRunner[Input, Option, Solution]) Run(ctx context.Context) error {
+	cfg := r.RunnerConfig()
+	switch cfg.Command() {
+	case CommandValidate:
+		return r.validateInputs(ctx, cfg)
+	case CommandSchema:
+		return r.printSchema()
+	case CommandVersion:
+		return printVersion()
+	case CommandOptions:
+		return r.printOptions()
+	}
+	if cfg.Runner.Input.Dir == "" && !cfg.Runner.Input.Lines {
+		return r.Runner.Run(ctx)
+	}
+	if err := validateBatch(cfg); err != nil {
+		return err
+	}
+	run := func(ctx context.Context) error {
+		if cfg.Runner.Input.Dir != "" {
+			return r.runDir(ctx, cfg)
+		}
+		return r.runLines(ctx, cfg)
+	}
+	if p, ok := r.Runner.(profiler); ok {
+		return p.profile(ctx, run)
+	}
+	return run(ctx)
+}
+
+// profiler is implemented by runners that record profiles around a function.
+type profiler interface {
+	profile(ctx context.Context, run func(context.Context) error) error
+}
+
+// validateBatch checks the configuration of batch mode. Compressed output is
+// not supported, since the outputs of the inputs are combined into JSON lines
+// or written to files named after the inputs.
+func validateBatch(cfg CLIRunnerConfig) error {
+	compression, err := outputCompression(cfg)
+	if err != nil {
+		return err
+	}
+	same, err := sameDir(cfg.Runner.Input.Dir, cfg.Runner.Output.Dir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.Runner.Input.Dir != "" && cfg.Runner.Input.Lines:
+		return errors.New("input dir and input lines cannot be used together")
+	case cfg.Runner.Input.Dir != "" && cfg.Runner.Input.Path != "":
+		return errors.New("input dir and input path cannot be used together")
+	case cfg.Runner.Output.Dir != "" && cfg.Runner.Input.Dir == "":
+		return errors.New("output dir can only be used with input dir")
+	case cfg.Runner.Output.Dir != "" && cfg.Runner.Output.Path != "":
+		return errors.New("output dir and output path cannot be used together")
+	case compression != CompressionNone:
+		return errors.New("compressed output is not supported in batch mode")
+	case same:
+		// the outputs would overwrite the inputs before they are read.
+		return errors.New("output dir cannot be the input dir")
+	}
+	return nil
+}
+
+// sameDir reports whether the directories a and b are the same. Empty paths
+// are never the same.
+func sameDir(a, b string) (bool, error) {
+	if a == "" || b == "" {
+		return false, nil
+	}
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	if absA == absB {
+		return true, nil
+	}
+	// different paths may still lead to the same directory, e.g. through a
+	// symbolic link.
+	infoA, errA := os.Stat(absA)
+	infoB, errB := os.Stat(absB)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB), nil
+}
+
+// runDir runs every file in the input directory. Outputs are written to a
+// file of the same name in the output directory or, if there is none, as one
+// JSON line per input. Only the last solution of an input is written as a
+// line, so that lines and inputs stay aligned.
+func (r *cliRunner[Input, Option, Solution]) runDir(
+	ctx context.Context, cfg CLIRunnerConfig,
+) error {
+	entries, err := os.ReadDir(cfg.Runner.Input.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	var lines *lineWriter
+	if cfg.Runner.Output.Dir != "" {
+		if err := os.MkdirAll(cfg.Runner.Output.Dir, 0o755); err != nil {
+			return err
+		}
+	} else {
+		writer, closeWriter, err := batchWriter(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeWriter()
+		lines = newLineWriter(writer)
+	}
+
+	b := newBatch(cfg.Runner.Batch.Workers)
+	lineCtx := withSolutions(ctx, Last)
+	for i, name := range names {
+		i, name := i, name
+		inputPath := filepath.Join(cfg.Runner.Input.Dir, name)
+		b.run(name, func() error {
+			if err := ctx.Err(); err != nil {
+				return lines.skip(i, err)
+			}
+			if lines == nil {
+				outputPath := filepath.Join(cfg.Runner.Output.Dir, name)
+				return runWith(ctx, r.Runner, fileIOProducer(inputPath, outputPath, nil))
+			}
+			var output bytes.Buffer
+			err := runWith(lineCtx, r.Runner, fileIOProducer(inputPath, "", &output))
+			return lines.write(i, output.Bytes(), err)
+		})
+	}
+	return b.wait(lines)
+}
+
+// runLines runs every non-empty line of the input file, or stdin, as a JSON
+// input. The outputs are written as one JSON line per input in the order of
+// the inputs. Only the last solution of an input is written, even if all
+// solutions are configured.
+func (r *cliRunner[Input, Option, Solution]) runLines(
+	ctx context.Context, cfg CLIRunnerConfig,
+) error {
+	var reader io.Reader = os.Stdin
+	if cfg.Runner.Input.Path != "" {
+		f, err := os.Open(cfg.Runner.Input.Path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		reader = f
+	}
+	writer, closeWriter, err := batchWriter(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+	lines := newLineWriter(writer)
+
+	b := newBatch(cfg.Runner.Batch.Workers)
+	ctx = withSolutions(ctx, Last)
+	buffered := bufio.NewReader(reader)
+	for lineNumber, i := 1, 0; ; lineNumber++ {
+		line, readErr := buffered.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			b.abort(fmt.Errorf("line %d: %w", lineNumber, readErr))
+			break
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			index := i
+			i++
+			b.run(fmt.Sprintf("line %d", lineNumber), func() error {
+				if err := ctx.Err(); err != nil {
+					return lines.skip(index, err)
+				}
+				var output bytes.Buffer
+				err := runWith(ctx, r.Runner,
+					func(_ context.Context, cfg CLIRunnerConfig) (IOData, error) {
+						option, err := optionsSource(cfg)
+						if err != nil {
+							return ioData{}, err
+						}
+						return NewIOData(bytes.NewReader(line), option, &output)
+					},
+				)
+				return lines.write(index, output.Bytes(), err)
+			})
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	return b.wait(lines)
+}
+
+// fileIOProducer returns an IOProducer that reads the input from inputPath
+// and writes the output to outputPath or, if it is empty, to writer.
+func fileIOProducer(
+	inputPath, outputPath string, writer io.Writer,
+) IOProducer[CLIRunnerConfig] {
+	return func(_ context.Context, cfg CLIRunnerConfig) (IOData, error) {
+		reader, err := os.Open(inputPath)
+		if err != nil {
+			return ioData{}, err
+		}
+		w, closeWriter := writer, func() {}
+		if outputPath != "" {
+			f, err := os.Create(outputPath)
+			if err != nil {
+				_ = reader.Close()
+				return ioData{}, err
+			}
+			w, closeWriter = f, func() { _ = f.Close() }
+		}
+		option, err := optionsSource(cfg)
+		if err != nil {
+			_ = reader.Close()
+			closeWriter()
+			return ioData{}, err
+		}
+		data, err := NewIOData(reader, option, w)
+		if err != nil {
+			closeWriter()
+		}
+		return data, err
+	}
+}
+
+// batchWriter returns the writer of the JSON lines output of a batch, which
+// is the output file or stdout.
+func batchWriter(cfg CLIRunnerConfig) (io.Writer, func(), error) {
+	if cfg.Runner.Output.Path == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.Create(cfg.Runner.Output.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+// batch runs the inputs of a batch with a limited number of workers and
+// collects their errors.
+type batch struct {
+	workers chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	total   int
+	// errs are the errors by the sequence number of the input.
+	errs map[int]error
+	// batchErrs are the errors of the batch itself, such as failing to read
+	// the inputs, which are not counted as failed inputs.
+	batchErrs []error
+}
+
+func newBatch(workers int) *batch {
+	if workers < 1 {
+		workers = 1
+	}
+	return &batch{workers: make(chan struct{}, workers), errs: map[int]error{}}
+}
+
+// run runs f once a worker is free.
+func (b *batch) run(name string, f func() error) {
+	b.mutex.Lock()
+	sequence := b.total
+	b.total++
+	b.mutex.Unlock()
+	b.workers <- struct{}{}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.workers }()
+		if err := f(); err != nil {
+			b.fail(sequence, fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+func (b *batch) fail(sequence int, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.errs[sequence] = err
+}
+
+// abort records an error of the batch that is not the failure of an input.
+func (b *batch) abort(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.batchErrs = append(b.batchErrs, err)
+}
+
+// wait waits for all inputs and returns a summary of the failed inputs, if
+// any, followed by the errors of the batch itself and of writing the output.
+func (b *batch) wait(lines *lineWriter) error {
+	b.wg.Wait()
+	var errs []error
+	for sequence := 0; sequence < b.total; sequence++ {
+		if err, ok := b.errs[sequence]; ok {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		summary := fmt.Errorf("%d of %d inputs failed", len(errs), b.total)
+		errs = append([]error{summary}, errs...)
+	}
+	errs = append(errs, b.batchErrs...)
+	if lines != nil && lines.err != nil {
+		errs = append(errs, fmt.Errorf("write output: %w", lines.err))
+	}
+	return errors.Join(errs...)
+}
+
+// lineWriter writes the results of a batch as JSON lines in the order of the
+// inputs, even though they finish in any order. Failed inputs are written as
+// an error line, so that lines and inputs stay aligned.
+type lineWriter struct {
+	mutex   sync.Mutex
+	writer  io.Writer
+	next    int
+	pending map[int][]byte
+	err     error
+}
+
+func newLineWriter(writer io.Writer) *lineWriter {
+	return &lineWriter{writer: writer, pending: map[int][]byte{}}
+}
+
+// write stores the result of the input with the given index and writes all
+// results that are next in order. It returns runErr, or an error if the
+// output is not JSON.
+func (l *lineWriter) write(index int, output []byte, runErr error) error {
+	line, err := jsonLines(output)
+	if runErr != nil {
+		err = runErr
+	}
+	if err != nil {
+		line, _ = json.Marshal(classify(err))
+		line = append(line, '\n')
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pending[index] = line
+	for {
+		line, ok := l.pending[l.next]
+		if !ok {
+			break
+		}
+		delete(l.pending, l.next)
+		l.next++
+		if l.err == nil {
+			_, l.err = l.writer.Write(line)
+		}
+	}
+	return err
+}
+
+// skip records an input that was not run because of err. A nil lineWriter,
+// used if outputs are written to files, only returns err.
+func (l *lineWriter) skip(index int, err error) error {
+	if l == nil {
+		return err
+	}
+	return l.write(index, nil, err)
+}
+
+// jsonLines compacts every JSON document in data onto a line of its own. A run
+// without any solution results in a null line.
+func jsonLines(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("null\n"), nil
+	}
+	var lines bytes.Buffer
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var document json.RawMessage
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return lines.Bytes(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("output is not JSON: %w", err)
+		}
+		if err := json.Compact(&lines, document); err != nil {
+			return nil, err
+		}
+		lines.WriteByte('\n')
+	}
+}
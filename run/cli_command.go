@@ -0,0 +1,173 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	humaSchema "github.com/danielgtaylor/huma/schema"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+// inputValidator is implemented by runners that can validate an input without
+// running the algorithm.
+type inputValidator interface {
+	validate(ctx context.Context, reader io.Reader) error
+}
+
+// validateInputs validates the input, or every input in batch mode, without
+// running the algorithm. The errors of all invalid inputs are returned.
+func (r *cliRunner[Input, Option, Solution]) validateInputs(
+	ctx context.Context, cfg CLIRunnerConfig,
+) error {
+	v, ok := r.Runner.(inputValidator)
+	if !ok {
+		return errors.New("runner cannot validate inputs")
+	}
+	switch {
+	case cfg.Runner.Input.Dir != "":
+		entries, err := os.ReadDir(cfg.Runner.Input.Dir)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			path := filepath.Join(cfg.Runner.Input.Dir, entry.Name())
+			if err := validateFile(ctx, v, path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			}
+		}
+		return errors.Join(errs...)
+	case cfg.Runner.Input.Lines:
+		return validateLines(ctx, v, cfg.Runner.Input.Path)
+	case cfg.Runner.Input.Path != "":
+		return validateFile(ctx, v, cfg.Runner.Input.Path)
+	default:
+		return v.validate(ctx, os.Stdin)
+	}
+}
+
+// validateFile validates the input in the file at path.
+func validateFile(ctx context.Context, v inputValidator, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return v.validate(ctx, f)
+}
+
+// validateLines validates every non-empty line of the file at path, or of
+// stdin, as a JSON input.
+func validateLines(ctx context.Context, v inputValidator, path string) error {
+	var reader io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		reader = f
+	}
+	var errs []error
+	buffered := bufio.NewReader(reader)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := buffered.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, readErr))
+			break
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := v.validate(ctx, bytes.NewReader(line)); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, err))
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// printSchema writes the JSON schemas of the input and the options, as used to
+// validate the input, to stdout.
+func (r *cliRunner[Input, Option, Solution]) printSchema() error {
+	input, err := humaSchema.Generate(reflect.TypeOf(new(Input)))
+	if err != nil {
+		return err
+	}
+	optionType, _ := optionSchemaType(reflect.TypeOf(new(Option)))
+	options, err := humaSchema.Generate(optionType)
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]*humaSchema.Schema{
+		"input":   input,
+		"options": options,
+	})
+}
+
+// optionSchemaType returns a type for the schema of an option type, in which
+// durations are strings such as "1s", as the options accept them. Since huma
+// reads the defaults of numbers as JSON, it cannot generate the schema of a
+// duration with a default otherwise. The second value reports whether the
+// type differs.
+func optionSchemaType(t reflect.Type) (reflect.Type, bool) {
+	switch {
+	case t == durationType:
+		return reflect.TypeOf(""), true
+	case t.Kind() == reflect.Pointer:
+		elem, changed := optionSchemaType(t.Elem())
+		return reflect.PointerTo(elem), changed
+	case t.Kind() == reflect.Slice:
+		elem, changed := optionSchemaType(t.Elem())
+		return reflect.SliceOf(elem), changed
+	case t.Kind() != reflect.Struct:
+		return t, false
+	}
+	fields := make([]reflect.StructField, 0, t.NumField())
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldType, fieldChanged := optionSchemaType(field.Type)
+		field.Type = fieldType
+		changed = changed || fieldChanged
+		fields = append(fields, field)
+	}
+	if !changed {
+		return t, false
+	}
+	return reflect.StructOf(fields), true
+}
+
+// printOptions writes the descriptions of the options as a markdown table to
+// stdout.
+func (r *cliRunner[Input, Option, Solution]) printOptions() error {
+	_, err := fmt.Fprint(os.Stdout, OptionsMarkdown(DescribeOptions[Option](r.envPrefix)))
+	return err
+}
+
+// printVersion writes the versions of the sdk and its known dependencies to
+// stdout.
+func printVersion() error {
+	return printJSON(schema.NewVersion())
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
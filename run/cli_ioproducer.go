@@ -8,27 +8,58 @@ import (
 
 // CliIOProducer is the IOProducer for the CliRunner. The input and output paths
 // are used to configure the input and output readers and writers. If the paths
-// are empty, os.Stdin and os.Stdout are used.
-func CliIOProducer(_ context.Context, cfg CLIRunnerConfig) (IOData, error) {
+// are empty, os.Stdin and os.Stdout are used. If an options file is configured,
+// it is the option source. On error, the files opened so far are closed.
+func CliIOProducer(_ context.Context, cfg CLIRunnerConfig) (_ IOData, err error) {
+	var opened []io.Closer
+	defer func() {
+		if err != nil {
+			for _, file := range opened {
+				_ = file.Close()
+			}
+		}
+	}()
 	reader := os.Stdin
 	if cfg.Runner.Input.Path != "" {
 		r, err := os.Open(cfg.Runner.Input.Path)
 		if err != nil {
 			return ioData{}, err
 		}
+		opened = append(opened, r)
 		reader = r
 	}
+	option, err := optionsSource(cfg)
+	if err != nil {
+		return ioData{}, err
+	}
+	if closer, ok := option.(io.Closer); ok {
+		opened = append(opened, closer)
+	}
 	var writer io.Writer = os.Stdout
 	if cfg.Runner.Output.Path != "" {
 		w, err := os.Create(cfg.Runner.Output.Path)
 		if err != nil {
 			return ioData{}, err
 		}
+		opened = append(opened, w)
 		writer = w
 	}
 	return NewIOData(
 		reader,
-		nil,
+		option,
 		writer,
 	)
 }
+
+// optionsSource opens the options file of the configuration. It returns nil if
+// no options file is configured.
+func optionsSource(cfg CLIRunnerConfig) (any, error) {
+	if cfg.Runner.Options.Path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(cfg.Runner.Options.Path)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
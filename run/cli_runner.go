@@ -8,24 +8,50 @@ import (
 
 // NewCLIRunner is the default CLI runner. It reads the input from stdin or a
 // file, writes output to stdout or a file, decodes the input using the JSON
-// decoder, accepts options from the command line, and encodes the solution
-// using the JSON encoder.
+// decoder, accepts options from the command line, environment variables and an
+// options file, and encodes the solution using the JSON encoder. Auxiliary
+// inputs are read by the algorithm with AuxiliaryInput. If an input directory
+// or JSON lines input is configured, the algorithm is run once per input in
+// batch mode. The commands validate, schema, version and options, given as the
+// first argument, validate the input or print the schemas of the input and the
+// options, the versions of the dependencies or a description of the options
+// instead, see Command.
 func NewCLIRunner[Input, Option, Solution any](
 	algorithm Algorithm[Input, Option, Solution],
 	options ...RunnerOption[CLIRunnerConfig, Input, Option, Solution],
 ) Runner[CLIRunnerConfig, Input, Option, Solution] {
-	runner := GenericRunner(
+	runner, err := NewCLIRunnerWith(OSCommandLine(), algorithm, options...)
+	exitOnError(err)
+	return runner
+}
+
+// NewCLIRunnerWith is NewCLIRunner for the given command line. Instead of
+// exiting the program, it returns an error if the command line cannot be
+// parsed.
+func NewCLIRunnerWith[Input, Option, Solution any](
+	commandLine CommandLine,
+	algorithm Algorithm[Input, Option, Solution],
+	options ...RunnerOption[CLIRunnerConfig, Input, Option, Solution],
+) (Runner[CLIRunnerConfig, Input, Option, Solution], error) {
+	runner, err := NewGenericRunner(
+		commandLine,
 		CliIOProducer,
 		GenericDecoder[Input](decode.JSON()),
 		validate.JSON[Input](nil),
-		NoopOptionsDecoder[Option],
+		FileOptionDecoder[Option],
 		algorithm,
 		GenericEncoder[Solution, Option](encode.JSON()),
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, option := range options {
 		option(runner)
 	}
 
-	return runner
+	return &cliRunner[Input, Option, Solution]{
+		Runner:    runner,
+		envPrefix: commandLine.EnvPrefix,
+	}, nil
 }
@@ -1,6 +1,10 @@
 package run
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // CPUProfiler is the interface a runner configuration can implement to return
 // the CPU profile path.
@@ -14,33 +18,108 @@ type MemoryProfiler interface {
 	MemoryProfilePath() string
 }
 
+// AllocsProfiler is the interface a runner configuration can implement to
+// return the allocation profile path.
+type AllocsProfiler interface {
+	AllocsProfilePath() string
+}
+
+// BlockProfiler is the interface a runner configuration can implement to
+// return the block profile path.
+type BlockProfiler interface {
+	BlockProfilePath() string
+}
+
+// MutexProfiler is the interface a runner configuration can implement to
+// return the mutex profile path.
+type MutexProfiler interface {
+	MutexProfilePath() string
+}
+
+// TraceProfiler is the interface a runner configuration can implement to
+// return the execution trace path.
+type TraceProfiler interface {
+	TraceProfilePath() string
+}
+
 // OutputPather is the interface a runner configuration can implement to return
 // the output path.
 type OutputPather interface {
 	OutputPath() string
 }
 
+// TimeLimiter is the interface a runner configuration can implement to limit
+// the duration of a run. A limit of zero means that the run is not limited.
+type TimeLimiter interface {
+	TimeLimit() time.Duration
+}
+
 // SolutionLimiter is the interface a runner configuration can implement to
 // control whether all or only the last solution is returned.
 type SolutionLimiter interface {
 	Solutions() (Solutions, error)
 }
 
+// AuxiliaryInputer is the interface a runner configuration can implement to
+// return the paths of auxiliary inputs by name. Algorithms read them with
+// AuxiliaryInput or DecodeAuxiliaryInput.
+type AuxiliaryInputer interface {
+	AuxiliaryInputs() map[string]string
+}
+
 // CLIRunnerConfig is the configuration of the  CliRunner.
 type CLIRunnerConfig struct {
 	Runner struct {
-		Input struct {
-			Path string `usage:"The input file path"`
+		Duration time.Duration `usage:"The maximum duration of a run, 0 means no limit"`
+		Input    struct {
+			Path  string `usage:"The input file path"`
+			Dir   string `usage:"The directory of input files, each file is run in batch mode"`
+			Lines bool   `usage:"Run each line of the input as a JSON input in batch mode"`
+			// Auxiliary are the paths of auxiliary inputs by name.
+			Auxiliary map[string]string `usage:"Auxiliary inputs as name=path pairs, e.g. matrix=matrix.json"`
+		}
+		Options struct {
+			Path string `usage:"The options file path (JSON or YAML), it takes precedence over flags"`
 		}
 		Profile struct {
 			CPU    string `usage:"The CPU profile file path"`
-			Memory string `usage:"The memory profile file path"`
+			Memory string `usage:"The memory profile file path, taken when the algorithm returns"`
+			Allocs string `usage:"The allocation profile file path, taken when the algorithm returns"`
+			Block  string `usage:"The block profile file path"`
+			Mutex  string `usage:"The mutex profile file path"`
+			Trace  string `usage:"The execution trace file path"`
 		}
 		Output struct {
 			Path      string `usage:"The output file path"`
+			Dir       string `usage:"The directory to write one output per input file to in batch mode"`
 			Solutions string `default:"last" usage:"{all, last}"`
+			// Compression is the compression of the output.
+			Compression string `usage:"{none, gzip, zstd}, by default chosen by the output path extension (.gz, .zst)"`
+		}
+		Batch struct {
+			Workers int `default:"1" usage:"The number of inputs run in parallel in batch mode"`
 		}
 	}
+	// command is the command given as the first argument, it is not a flag.
+	command Command
+}
+
+// Command returns the command of the run, CommandSolve by default.
+func (c CLIRunnerConfig) Command() Command {
+	if c.command == "" {
+		return CommandSolve
+	}
+	return c.command
+}
+
+func (c *CLIRunnerConfig) setCommand(name string) error {
+	command, err := ParseCommand(name)
+	c.command = command
+	return err
+}
+
+func (c CLIRunnerConfig) commandUsage() string {
+	return commandUsage
 }
 
 // OutputPath returns the output path.
@@ -48,6 +127,11 @@ func (c CLIRunnerConfig) OutputPath() string {
 	return c.Runner.Output.Path
 }
 
+// AuxiliaryInputs returns the paths of the auxiliary inputs by name.
+func (c CLIRunnerConfig) AuxiliaryInputs() map[string]string {
+	return c.Runner.Input.Auxiliary
+}
+
 // CPUProfilePath returns the CPU profile path.
 func (c CLIRunnerConfig) CPUProfilePath() string {
 	return c.Runner.Profile.CPU
@@ -58,6 +142,36 @@ func (c CLIRunnerConfig) MemoryProfilePath() string {
 	return c.Runner.Profile.Memory
 }
 
+// AllocsProfilePath returns the allocation profile path.
+func (c CLIRunnerConfig) AllocsProfilePath() string {
+	return c.Runner.Profile.Allocs
+}
+
+// BlockProfilePath returns the block profile path.
+func (c CLIRunnerConfig) BlockProfilePath() string {
+	return c.Runner.Profile.Block
+}
+
+// MutexProfilePath returns the mutex profile path.
+func (c CLIRunnerConfig) MutexProfilePath() string {
+	return c.Runner.Profile.Mutex
+}
+
+// TraceProfilePath returns the execution trace path.
+func (c CLIRunnerConfig) TraceProfilePath() string {
+	return c.Runner.Profile.Trace
+}
+
+// TimeLimit returns the maximum duration of a run.
+func (c CLIRunnerConfig) TimeLimit() time.Duration {
+	return c.Runner.Duration
+}
+
+// OutputCompression returns the configured compression of the output.
+func (c CLIRunnerConfig) OutputCompression() (Compression, error) {
+	return ParseCompression(c.Runner.Output.Compression)
+}
+
 // Solutions returns the configured solutions.
 func (c CLIRunnerConfig) Solutions() (Solutions, error) {
 	return ParseSolutions(c.Runner.Output.Solutions)
@@ -92,3 +206,42 @@ func ParseSolutions(s string) (Solutions, error) {
 		return Last, errors.New(`solutions must be "all" or "last"`)
 	}
 }
+
+// Command is a command of the CLIRunner, given as the first argument before
+// the flags, e.g. "app validate -runner.input.path input.json".
+type Command string
+
+// Commands of the CLIRunner.
+const (
+	// CommandSolve runs the algorithm, it is the default command.
+	CommandSolve Command = "solve"
+	// CommandValidate validates the input without running the algorithm.
+	CommandValidate Command = "validate"
+	// CommandSchema prints the JSON schemas of the input and the options.
+	CommandSchema Command = "schema"
+	// CommandVersion prints the versions of the sdk and its known
+	// dependencies.
+	CommandVersion Command = "version"
+	// CommandOptions prints the options, their defaults and constraints as a
+	// markdown table, see DescribeOptions.
+	CommandOptions Command = "options"
+)
+
+const commandUsage = `Commands:
+  solve     Run the algorithm (default)
+  validate  Validate the input without running the algorithm
+  schema    Print the JSON schemas of the input and the options
+  version   Print the versions of the dependencies
+  options   Print the options as a markdown table
+`
+
+// ParseCommand converts the name of a command to a Command.
+func ParseCommand(name string) (Command, error) {
+	switch command := Command(name); command {
+	case CommandSolve, CommandValidate, CommandSchema, CommandVersion,
+		CommandOptions:
+		return command, nil
+	default:
+		return CommandSolve, fmt.Errorf("unknown command %q", name)
+	}
+}
@@ -0,0 +1,168 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression format of an input or an output. The zero
+// value means that the compression is not set, in which case the compression
+// of an output is chosen by the extension of its path.
+type Compression string
+
+// Compression formats. Inputs are decompressed automatically, the format is
+// detected by its magic bytes. Outputs can be compressed with gzip and zstd.
+const (
+	// CompressionNone means that the data is not compressed.
+	CompressionNone Compression = "none"
+	// CompressionGzip is the gzip format, with the .gz extension.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd is the Zstandard format, with the .zst extension.
+	CompressionZstd Compression = "zstd"
+	// CompressionBzip2 is the bzip2 format, with the .bz2 extension. It is only
+	// supported for inputs.
+	CompressionBzip2 Compression = "bzip2"
+)
+
+// ParseCompression converts "none", "gzip" and "zstd" to a Compression. The
+// file extensions "gz" and "zst" are accepted as well. An empty string is
+// converted to the zero value, which means that the compression is not set.
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "none":
+		return CompressionNone, nil
+	case "gzip", "gz":
+		return CompressionGzip, nil
+	case "zstd", "zst":
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf(
+			`output compression must be "none", "gzip" or "zstd", got %q`, s,
+		)
+	}
+}
+
+// compressionOf returns the compression of a file by its extension.
+func compressionOf(path string) Compression {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return CompressionZstd
+	case strings.HasSuffix(path, ".bz2"):
+		return CompressionBzip2
+	}
+	return CompressionNone
+}
+
+// trimCompressionExt removes the extension of the compression from a path,
+// e.g. "matrix.csv.zst" becomes "matrix.csv".
+func trimCompressionExt(path string) string {
+	for _, ext := range []string{".gz", ".zst", ".bz2"} {
+		if strings.HasSuffix(path, ext) {
+			return strings.TrimSuffix(path, ext)
+		}
+	}
+	return path
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// detectCompression returns the compression of the data of the reader by its
+// magic bytes. It does not consume the data.
+func detectCompression(reader *bufio.Reader) Compression {
+	// the error is irrelevant, fewer bytes are returned on a short input.
+	magic, _ := reader.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(magic, zstdMagic):
+		return CompressionZstd
+	case bytes.HasPrefix(magic, bzip2Magic) && len(magic) == 4 &&
+		magic[3] >= '1' && magic[3] <= '9':
+		return CompressionBzip2
+	}
+	return CompressionNone
+}
+
+// decompress returns a reader of the decompressed data of the reader. The
+// compression is detected by its magic bytes, uncompressed data is returned
+// as is. Closing the returned reader does not close the given one.
+func decompress(reader io.Reader) (io.ReadCloser, error) {
+	bufferedReader := bufio.NewReader(reader)
+	switch detectCompression(bufferedReader) {
+	case CompressionGzip:
+		return gzip.NewReader(bufferedReader)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(
+			bufferedReader, zstd.WithDecoderConcurrency(1),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(bufferedReader)), nil
+	}
+	return io.NopCloser(bufferedReader), nil
+}
+
+// compressWriter is a writer that compresses the data written to it. Flush
+// writes all pending data, Close writes the end of the compressed stream. Both
+// leave the underlying writer open.
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newCompressWriter returns a writer that compresses the data written to it
+// and writes it to writer. It returns nil if there is no compression.
+func newCompressWriter(
+	writer io.Writer, compression Compression,
+) (compressWriter, error) {
+	switch compression {
+	case "", CompressionNone:
+		return nil, nil
+	case CompressionGzip:
+		return gzip.NewWriter(writer), nil
+	case CompressionZstd:
+		return zstd.NewWriter(writer, zstd.WithEncoderConcurrency(1))
+	}
+	return nil, fmt.Errorf("%s compression is not supported for outputs", compression)
+}
+
+// OutputCompressor is the interface a runner configuration can implement to
+// return the compression of the output. It takes precedence over the extension
+// of the output path.
+type OutputCompressor interface {
+	OutputCompression() (Compression, error)
+}
+
+// outputCompression returns the compression of the output of a run. The
+// configured compression, including an explicit CompressionNone, takes
+// precedence over the extension of the output path.
+func outputCompression(runnerCfg any) (Compression, error) {
+	if compressor, ok := runnerCfg.(OutputCompressor); ok {
+		compression, err := compressor.OutputCompression()
+		if err != nil || compression != "" {
+			return compression, err
+		}
+	}
+	if outputPather, ok := runnerCfg.(OutputPather); ok {
+		return compressionOf(outputPather.OutputPath()), nil
+	}
+	return CompressionNone, nil
+}
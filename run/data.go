@@ -0,0 +1,75 @@
+package run
+
+import (
+	"context"
+	"sync"
+)
+
+// RequestID is the key of the id of the request of a run of the HTTPRunner in
+// the data of the run. It can be read with Get[string].
+const RequestID = "request_id"
+
+// Set stores a value under the given key in the data of the run, see Data.
+// It does nothing if the context does not carry the data of a run.
+func Set[T any](ctx context.Context, key string, value T) {
+	if data, ok := ctx.Value(Data).(*sync.Map); ok {
+		data.Store(key, value)
+	}
+}
+
+// Get returns the value stored under the given key in the data of the run,
+// see Data. It returns false if there is no value of type T under the key.
+func Get[T any](ctx context.Context, key string) (T, bool) {
+	data, ok := ctx.Value(Data).(*sync.Map)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	value, ok := data.Load(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := value.(T)
+	return t, ok
+}
+
+// Export marks the given keys of the data of the run to be exported: if the
+// solution is a schema.Output, their values are added to the custom run
+// statistics of the output. If the algorithm sets the custom run statistics
+// to a map[string]any, its values take precedence over exported values of
+// the same key. Custom run statistics of other types are left as they are.
+func Export(ctx context.Context, keys ...string) {
+	if r, ok := ctx.Value(statisticsKey{}).(*statisticsRecorder); ok {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		for _, key := range keys {
+			r.exported[key] = true
+		}
+	}
+}
+
+// withData returns a context that carries the data of a run. The data of the
+// context is reused, e.g. the data of an http request, if there is one.
+func withData(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(Data).(*sync.Map); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, Data, &sync.Map{})
+}
+
+// exportedData returns the values of the exported keys of the data of the
+// run. Keys without a value are left out.
+func (r *statisticsRecorder) exportedData(ctx context.Context) map[string]any {
+	data, ok := ctx.Value(Data).(*sync.Map)
+	if !ok || len(r.exported) == 0 {
+		return nil
+	}
+	exported := map[string]any{}
+	for key := range r.exported {
+		if value, ok := data.Load(key); ok {
+			exported[key] = value
+		}
+	}
+	return exported
+}
@@ -0,0 +1,95 @@
+package run_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/runtest"
+	"github.com/nextmv-io/sdk/run/schema"
+	"github.com/nextmv-io/sdk/run/statistics"
+)
+
+func TestSetGet(t *testing.T) {
+	ctx := context.WithValue(context.Background(), run.Data, &sync.Map{})
+	run.Set(ctx, "count", 3)
+	if got, ok := run.Get[int](ctx, "count"); !ok || got != 3 {
+		t.Errorf("got %v, %v; want %v, %v", got, ok, 3, true)
+	}
+	if got, ok := run.Get[string](ctx, "count"); ok {
+		t.Errorf("got %v, %v; want %v, %v", got, ok, "", false)
+	}
+	if got, ok := run.Get[int](ctx, "missing"); ok {
+		t.Errorf("got %v, %v; want %v, %v", got, ok, 0, false)
+	}
+}
+
+func TestGetWithoutData(t *testing.T) {
+	ctx := context.Background()
+	run.Set(ctx, "count", 3)
+	if got, ok := run.Get[int](ctx, "count"); ok {
+		t.Errorf("got %v, %v; want %v, %v", got, ok, 0, false)
+	}
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		custom any
+		want   any
+	}{
+		{nil, map[string]any{"count": 3.0, "name": "exported"}},
+		{
+			map[string]any{"name": "algorithm", "other": true},
+			map[string]any{"count": 3.0, "name": "algorithm", "other": true},
+		},
+		{"algorithm", "algorithm"},
+	}
+	for _, test := range tests {
+		custom := test.custom
+		algorithm := func(
+			ctx context.Context, _ any, _ struct{}, solutions chan<- schema.Output,
+		) error {
+			run.Set(ctx, "count", 3)
+			run.Set(ctx, "name", "exported")
+			run.Export(ctx, "count", "name", "missing")
+			output := schema.NewOutput[any](nil)
+			output.Statistics = statistics.NewStatistics()
+			output.Statistics.Run = &statistics.Run{Custom: custom}
+			solutions <- output
+			return nil
+		}
+		output, err := runtest.CLI(context.Background(), algorithm, []byte("{}"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := output.Statistics.Run.Custom; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %v; want %v", got, test.want)
+		}
+	}
+}
+
+func TestStatisticsNotModified(t *testing.T) {
+	stats := statistics.NewStatistics()
+	stats.Run = &statistics.Run{}
+	algorithm := func(
+		_ context.Context, _ any, _ struct{}, solutions chan<- schema.Output,
+	) error {
+		output := schema.NewOutput[any](nil)
+		output.Statistics = stats
+		solutions <- output
+		return nil
+	}
+	output, err := runtest.CLI(context.Background(), algorithm, []byte("{}"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Statistics.Run.Duration == nil || output.Statistics.Result == nil {
+		t.Errorf("got %v; want filled in run and result statistics", output.Statistics)
+	}
+	if stats.Run.Duration != nil || stats.Result != nil {
+		t.Errorf("got %v, %v; want the statistics of the algorithm unchanged",
+			stats.Run.Duration, stats.Result)
+	}
+}
@@ -0,0 +1,182 @@
+package decode
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nextmv-io/sdk/run/internal/columns"
+)
+
+// CSV creates a CSV decoder.
+func CSV() Decoder {
+	return CSVDecoder{Comma: ','}
+}
+
+// CSVDecoder is a Decoder that decodes CSV into a slice of structs. The first
+// record is the header. Columns are mapped to struct fields by the csv tag,
+// then by the json tag and otherwise by the field name, ignoring case. Columns
+// without a field are ignored. Values are converted to the type of the field:
+// strings, booleans, integers, floats, time.Duration and types implementing
+// encoding.TextUnmarshaler, such as time.Time, are supported. Empty values
+// leave the field at its zero value, or nil for pointers.
+type CSVDecoder struct {
+	// Comma is the field delimiter.
+	Comma rune
+}
+
+// Decode decodes CSV to the slice v points to.
+func (c CSVDecoder) Decode(r io.Reader, v any) error {
+	slice, err := sliceOf(v)
+	if err != nil {
+		return err
+	}
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode CSV into %s", slice.Type())
+	}
+
+	reader := csv.NewReader(r)
+	if c.Comma != 0 {
+		reader.Comma = c.Comma
+	}
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields := map[string]int{}
+	for _, column := range columns.Of(structType) {
+		fields[strings.ToLower(column.Name)] = column.Index
+	}
+	indices := make([]int, len(header))
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.TrimSpace(name)
+		index, ok := fields[strings.ToLower(names[i])]
+		if !ok {
+			index = -1
+		}
+		indices[i] = index
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		elem := reflect.New(structType).Elem()
+		for i, value := range record {
+			if i >= len(indices) || indices[i] < 0 {
+				continue
+			}
+			if err := setValue(elem.Field(indices[i]), value); err != nil {
+				return fmt.Errorf("line %d, column %q: %w", line, names[i], err)
+			}
+		}
+		if elemType.Kind() == reflect.Pointer {
+			elem = elem.Addr()
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+}
+
+// ContentType returns the content type of the decoder.
+func (c CSVDecoder) ContentType() string {
+	return "text/csv"
+}
+
+// sliceOf returns the slice v points to, possibly through interfaces.
+func sliceOf(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		if rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Slice {
+			return rv.Elem(), nil
+		}
+		rv = rv.Elem()
+	}
+	return reflect.Value{}, fmt.Errorf("cannot decode into %T, a pointer to a slice is needed", v)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setValue converts s to the type of v and sets it.
+func setValue(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
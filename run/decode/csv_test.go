@@ -0,0 +1,68 @@
+package decode_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run/decode"
+)
+
+type order struct {
+	ID       string        `csv:"id"`
+	Quantity int           `json:"quantity"`
+	Weight   *float64      `csv:"weight"`
+	Express  bool          `csv:"express"`
+	Window   time.Duration `csv:"window"`
+	Due      time.Time     `csv:"due"`
+	Ignored  string        `csv:"-"`
+}
+
+func TestCSVDecoder(t *testing.T) {
+	input := "ID,quantity,weight,express,window,due,unknown\n" +
+		"a,2,1.5,true,1h,2023-01-02T15:04:05Z,x\n" +
+		"b,3,,false,,,\n"
+	var got []order
+	if err := decode.CSV().Decode(strings.NewReader(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	weight := 1.5
+	want := []order{
+		{
+			ID:       "a",
+			Quantity: 2,
+			Weight:   &weight,
+			Express:  true,
+			Window:   time.Hour,
+			Due:      time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{ID: "b", Quantity: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v; want %+v", got, want)
+	}
+}
+
+func TestCSVDecoderRowError(t *testing.T) {
+	input := "id,quantity\na,1\nb,many\n"
+	var got []order
+	err := decode.CSV().Decode(strings.NewReader(input), &got)
+	want := `line 3, column "quantity": invalid integer "many"`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v; want %v", err, want)
+	}
+}
+
+func TestJSONLDecoder(t *testing.T) {
+	input := "{\"id\": \"a\"}\n\n{\"id\": \"b\"}"
+	var got []struct {
+		ID string `json:"id"`
+	}
+	if err := decode.JSONL().Decode(strings.NewReader(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Errorf("got %+v; want ids a and b", got)
+	}
+}
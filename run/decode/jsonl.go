@@ -0,0 +1,51 @@
+package decode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// JSONL creates a JSON Lines decoder.
+func JSONL() Decoder {
+	return JSONLDecoder{}
+}
+
+// JSONLDecoder is a Decoder that decodes JSON Lines into a slice. Every
+// non-empty line is decoded into an element of the slice.
+type JSONLDecoder struct{}
+
+// Decode decodes JSON Lines to the slice v points to.
+func (j JSONLDecoder) Decode(r io.Reader, v any) error {
+	slice, err := sliceOf(v)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			elem := reflect.New(slice.Type().Elem())
+			if err := json.Unmarshal(b, elem.Interface()); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// ContentType returns the content type of the decoder. It is the media type of
+// the JSON lines an http runner streams, see run.MediaTypeNDJSON.
+func (j JSONLDecoder) ContentType() string {
+	return "application/x-ndjson"
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"io"
 	"net/url"
+	"reflect"
+	"strings"
 
 	"github.com/gorilla/schema"
 	"github.com/nextmv-io/sdk/run/decode"
+	"gopkg.in/yaml.v3"
 )
 
 // GenericDecoder returns a new generic decoder.
@@ -47,9 +50,58 @@ func NoopOptionsDecoder[Option any](
 	return option, nil
 }
 
-// QueryParamDecoder is a Decoder that returns option from query params.
+// FileOptionDecoder is a Decoder that reads the option from a JSON or YAML
+// document, such as the options file of the CLIRunner. Durations may be given
+// as strings, such as "1m30s". The fields present in the document take
+// precedence over flags and environment variables, the other fields keep
+// their values. If there is no document, the option is not changed.
+func FileOptionDecoder[Option any](
+	ctx context.Context, reader any,
+) (option Option, err error) {
+	if reader == nil {
+		return option, nil
+	}
+	ioReader, ok := reader.(io.Reader)
+	if !ok {
+		return option, errors.New(
+			"FileOptionDecoder is not compatible with configured IOProducer",
+		)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer func() {
+			tempErr := closer.Close()
+			// the first error is the most important
+			if err == nil {
+				err = tempErr
+			}
+		}()
+	}
+
+	// YAML is a superset of JSON, so both are decoded by the YAML decoder.
+	var document map[string]any
+	err = yaml.NewDecoder(ioReader).Decode(&document)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return option, err
+	}
+	value := reflect.ValueOf(&option).Elem()
+	for _, field := range optionFields(value.Type()) {
+		v, ok := field.lookup(document)
+		if !ok {
+			continue
+		}
+		if err := field.set(value, v); err != nil {
+			return option, err
+		}
+		markOptionSet(ctx, field.flag, OptionSourceFile)
+	}
+	return option, nil
+}
+
+// QueryParamDecoder is a Decoder that returns option from query params. The
+// fields present in the query take precedence over flags and environment
+// variables, the other fields keep their values.
 func QueryParamDecoder[Option any](
-	_ context.Context, reader any,
+	ctx context.Context, reader any,
 ) (option Option, err error) {
 	urlValues, ok := reader.(url.Values)
 	if !ok {
@@ -64,5 +116,18 @@ func QueryParamDecoder[Option any](
 
 	decoder := schema.NewDecoder()
 	err = decoder.Decode(&option, urlValues)
-	return option, err
+	if err != nil {
+		return option, err
+	}
+	t := reflect.TypeOf(option)
+	for _, field := range optionFields(t) {
+		key := field.queryKey(t)
+		for param := range urlValues {
+			if strings.EqualFold(param, key) {
+				markOptionSet(ctx, field.flag, OptionSourceRequest)
+				break
+			}
+		}
+	}
+	return option, nil
 }
@@ -1,4 +1,24 @@
 /*
 Package run provides tools for running solvers.
+
+# Option constraints
+
+The fields of an option struct can be constrained with tags. The option of a
+run is checked once the values of all sources are merged, before the
+algorithm runs. Violations are reported like those of an invalid input, with
+the ErrorCodeOption.
+
+	type option struct {
+		Duration time.Duration `default:"1s" min:"0s" max:"1m"`
+		Strategy string        `default:"fast" enum:"fast,thorough"`
+		Name     string        `required:"true"`
+	}
+
+The min and max tags bound numbers and durations, or the length of strings,
+slices and maps. The enum tag lists the allowed values, or the allowed
+elements of a slice. A required field must not be zero. The other
+constraints do not apply to zero values, so a field without a default can be
+left out unless it is required. DescribeOptions describes the options
+together with their constraints.
 */
 package run
@@ -0,0 +1,108 @@
+package encode
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/nextmv-io/sdk/run/internal/columns"
+)
+
+// CSV returns a new encoder that writes CSV.
+func CSV() Encoder {
+	return CSVEncoder{Comma: ','}
+}
+
+// CSVEncoder is an Encoder that encodes a slice of structs into CSV, with a
+// header followed by one record per element. Columns are named by the csv
+// tag, then by the json tag and otherwise by the field name. Every call of
+// Encode writes a header, so a stream of solutions results in one table per
+// solution.
+type CSVEncoder struct {
+	// Comma is the field delimiter.
+	Comma rune
+}
+
+// Encode writes the CSV encoding of the slice v to the w stream.
+func (c CSVEncoder) Encode(w io.Writer, v any) error {
+	slice := reflect.ValueOf(v)
+	for slice.Kind() == reflect.Pointer || slice.Kind() == reflect.Interface {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
+		return fmt.Errorf("cannot encode %T as CSV, a slice is needed", v)
+	}
+	structType := slice.Type().Elem()
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot encode %T as CSV, a slice of structs is needed", v)
+	}
+
+	writer := csv.NewWriter(w)
+	if c.Comma != 0 {
+		writer.Comma = c.Comma
+	}
+	cols := columns.Of(structType)
+	record := make([]string, len(cols))
+	for i, column := range cols {
+		record[i] = column.Name
+	}
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		for j, column := range cols {
+			value, err := formatValue(elem.Field(column.Index))
+			if err != nil {
+				return fmt.Errorf("record %d: %s: %w", i, column.Name, err)
+			}
+			record[j] = value
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ContentType returns the content type of the encoder.
+func (c CSVEncoder) ContentType() string {
+	return "text/csv"
+}
+
+// formatValue formats a field as a CSV value. Nil pointers are empty.
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err := value.MarshalText()
+		return string(b), err
+	case fmt.Stringer:
+		return value.String(), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
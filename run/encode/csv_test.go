@@ -0,0 +1,65 @@
+package encode_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run/encode"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	type stop struct {
+		ID      string        `csv:"id"`
+		Arrival *time.Time    `json:"arrival"`
+		Wait    time.Duration `csv:"wait"`
+		Load    float64
+		Ignored string `csv:"-"`
+	}
+	arrival := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	stops := []stop{
+		{ID: "a", Arrival: &arrival, Wait: time.Minute, Load: 1.5},
+		{ID: "b, c", Load: 2},
+	}
+	var b bytes.Buffer
+	if err := encode.CSV().Encode(&b, stops); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,arrival,wait,Load\n" +
+		"a,2023-01-02T15:04:05Z,1m0s,1.5\n" +
+		"\"b, c\",,0s,2\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+// failingText is a value that cannot be marshaled as text.
+type failingText struct{}
+
+func (failingText) MarshalText() ([]byte, error) {
+	return nil, errors.New("cannot marshal")
+}
+
+func TestCSVEncoderMarshalTextError(t *testing.T) {
+	type stop struct {
+		ID    string      `csv:"id"`
+		Value failingText `csv:"value"`
+	}
+	err := encode.CSV().Encode(io.Discard, []stop{{ID: "a"}})
+	if err == nil || !strings.Contains(err.Error(), "cannot marshal") {
+		t.Errorf("got %v; want %v", err, "cannot marshal")
+	}
+}
+
+func TestJSONLEncoder(t *testing.T) {
+	var b bytes.Buffer
+	if err := encode.JSONL().Encode(&b, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "1\n2\n"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
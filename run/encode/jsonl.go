@@ -0,0 +1,40 @@
+package encode
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// JSONL returns a new encoder that writes JSON Lines.
+func JSONL() Encoder {
+	return JSONLEncoder{}
+}
+
+// JSONLEncoder is an Encoder that writes every element of a slice as a JSON
+// line. Values that are not slices are written as a single line.
+type JSONLEncoder struct{}
+
+// Encode writes the JSON Lines encoding of v to the w stream.
+func (j JSONLEncoder) Encode(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	slice := reflect.ValueOf(v)
+	for slice.Kind() == reflect.Pointer && !slice.IsNil() {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
+		return encoder.Encode(v)
+	}
+	for i := 0; i < slice.Len(); i++ {
+		if err := encoder.Encode(slice.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContentType returns the content type of the encoder. It is the media type of
+// the JSON lines an http runner streams, see run.MediaTypeNDJSON.
+func (j JSONLEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
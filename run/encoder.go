@@ -1,11 +1,10 @@
 package run
 
 import (
-	"compress/gzip"
 	"context"
 	"errors"
 	"io"
-	"strings"
+	"net/http"
 
 	"github.com/nextmv-io/sdk/run/encode"
 )
@@ -23,11 +22,13 @@ type genericEncoder[Solution, Options any] struct {
 	encoder encode.Encoder
 }
 
-// Encode encodes the solution using the given encoder. If a given output path
-// ends in .gz, it will be gzipped after encoding. The writer needs to be an
-// io.Writer.
+// Encode encodes the solution using the given encoder. The output is
+// compressed as configured, or by the extension of the output path: .gz is
+// gzipped and .zst is compressed with zstd. The writer needs to be an
+// io.Writer. Every solution is flushed as soon as it is encoded, if the writer
+// supports flushing, so that improving solutions can be followed live.
 func (g *genericEncoder[Solution, Options]) Encode(
-	_ context.Context,
+	ctx context.Context,
 	solutions <-chan Solution,
 	writer any,
 	runnerCfg any,
@@ -50,33 +51,44 @@ func (g *genericEncoder[Solution, Options]) Encode(
 		return err
 	}
 
-	if outputPather, ok := runnerCfg.(OutputPather); ok {
-		if strings.HasSuffix(outputPather.OutputPath(), ".gz") {
-			ioWriter = gzip.NewWriter(ioWriter)
-		}
+	compression, err := outputCompression(runnerCfg)
+	if err != nil {
+		return err
+	}
+	compressor, err := newCompressWriter(ioWriter, compression)
+	if err != nil {
+		return err
+	}
+	if compressor != nil {
+		// the compressor must be closed before the writer it wraps.
+		defer func() {
+			tempErr := compressor.Close()
+			// the first error is the most important
+			if err == nil {
+				err = tempErr
+			}
+		}()
+		ioWriter = compressor
 	}
 
-	if limiter, ok := runnerCfg.(SolutionLimiter); ok {
-		solutionFlag, retErr := limiter.Solutions()
-		if retErr != nil {
-			return retErr
+	solutionFlag, err := solutionsMode(ctx, runnerCfg)
+	if err != nil {
+		return err
+	}
+	if solutionFlag == Last {
+		var last Solution
+		lastIsSet := false
+		for solution := range solutions {
+			last = solution
+			lastIsSet = true
 		}
-
-		if solutionFlag == Last {
-			var last Solution
-			lastIsSet := false
-			for solution := range solutions {
-				last = solution
-				lastIsSet = true
-			}
-			if !lastIsSet {
-				return nil
-			}
-			tempSolutions := make(chan Solution, 1)
-			tempSolutions <- last
-			close(tempSolutions)
-			solutions = tempSolutions
+		if !lastIsSet {
+			return nil
 		}
+		tempSolutions := make(chan Solution, 1)
+		tempSolutions <- last
+		close(tempSolutions)
+		solutions = tempSolutions
 	}
 
 	for solution := range solutions {
@@ -84,6 +96,46 @@ func (g *genericEncoder[Solution, Options]) Encode(
 		if err != nil {
 			return err
 		}
+		if compressor != nil {
+			if err := compressor.Flush(); err != nil {
+				return err
+			}
+		}
+		if err := flush(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type solutionsKey struct{}
+
+// withSolutions returns a context that overrides the solutions of the runner
+// configuration for a single run, e.g. to stream all solutions of a request.
+func withSolutions(ctx context.Context, solutions Solutions) context.Context {
+	return context.WithValue(ctx, solutionsKey{}, solutions)
+}
+
+// solutionsMode returns whether all or only the last solution is encoded. The
+// context takes precedence over the runner configuration. By default, all
+// solutions are encoded.
+func solutionsMode(ctx context.Context, runnerCfg any) (Solutions, error) {
+	if solutions, ok := ctx.Value(solutionsKey{}).(Solutions); ok {
+		return solutions, nil
+	}
+	if limiter, ok := runnerCfg.(SolutionLimiter); ok {
+		return limiter.Solutions()
+	}
+	return All, nil
+}
+
+// flush flushes the writer, if it supports flushing.
+func flush(writer any) error {
+	switch f := writer.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case http.Flusher:
+		f.Flush()
 	}
 	return nil
 }
@@ -0,0 +1,244 @@
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/nextmv-io/sdk/run/validate"
+)
+
+// ErrorCode classifies the errors of a run.
+type ErrorCode string
+
+// Error codes of a run. Each code maps to an HTTP status and to an exit code.
+//
+//	code                    status  exit code
+//	input_validation        422     3
+//	decode                  400     2
+//	option                  400     4
+//	algorithm               500     5
+//	encode                  500     6
+//	timeout                 504     7
+//	unsupported_media_type  415     1
+//	not_acceptable          406     1
+//	too_many_requests       429     1
+//	unavailable             503     1
+//	profile                 400     1
+//	profile_conflict        409     1
+//	not_found               404     1
+//	method_not_allowed      405     1
+//	no_result               409     1
+//
+// The codes with exit code 1 reject http requests, so they do not occur in a
+// CLI application. Errors that are not classified
+// map to status 500 and exit code 1.
+const (
+	// ErrorCodeInputValidation means the input does not pass validation.
+	ErrorCodeInputValidation ErrorCode = "input_validation"
+	// ErrorCodeDecode means the input could not be read or decoded.
+	ErrorCodeDecode ErrorCode = "decode"
+	// ErrorCodeOption means the option could not be decoded or is invalid.
+	ErrorCodeOption ErrorCode = "option"
+	// ErrorCodeAlgorithm means the algorithm failed.
+	ErrorCodeAlgorithm ErrorCode = "algorithm"
+	// ErrorCodeEncode means the solutions could not be encoded.
+	ErrorCodeEncode ErrorCode = "encode"
+	// ErrorCodeTimeout means the algorithm failed after the time limit of the
+	// run was reached.
+	ErrorCodeTimeout ErrorCode = "timeout"
+	// ErrorCodeUnsupportedMediaType means the content type or the content
+	// encoding of an http request is not supported.
+	ErrorCodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	// ErrorCodeNotAcceptable means none of the media types an http request
+	// accepts is supported.
+	ErrorCodeNotAcceptable ErrorCode = "not_acceptable"
+	// ErrorCodeTooManyRequests means an http request found neither a free
+	// slot nor a place in the queue in time.
+	ErrorCodeTooManyRequests ErrorCode = "too_many_requests"
+	// ErrorCodeUnavailable means the http server is shutting down and does
+	// not accept new runs.
+	ErrorCodeUnavailable ErrorCode = "unavailable"
+	// ErrorCodeProfile means the profiles an http request asks for are
+	// unknown, see ProfileHeader.
+	ErrorCodeProfile ErrorCode = "profile"
+	// ErrorCodeProfileConflict means an http request asks for profiles while
+	// another run is being profiled.
+	ErrorCodeProfileConflict ErrorCode = "profile_conflict"
+	// ErrorCodeNotFound means the path of an http request, or the
+	// asynchronous run it asks for, does not exist.
+	ErrorCodeNotFound ErrorCode = "not_found"
+	// ErrorCodeMethodNotAllowed means the path of an http request does not
+	// support its method.
+	ErrorCodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	// ErrorCodeNoResult means the result of an asynchronous run is asked for
+	// before the run succeeded.
+	ErrorCodeNoResult ErrorCode = "no_result"
+)
+
+// HTTPStatus returns the HTTP status that corresponds to the error code.
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case ErrorCodeInputValidation:
+		return http.StatusUnprocessableEntity
+	case ErrorCodeDecode, ErrorCodeOption, ErrorCodeProfile:
+		return http.StatusBadRequest
+	case ErrorCodeTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorCodeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ErrorCodeNotAcceptable:
+		return http.StatusNotAcceptable
+	case ErrorCodeTooManyRequests:
+		return http.StatusTooManyRequests
+	case ErrorCodeUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorCodeProfileConflict, ErrorCodeNoResult:
+		return http.StatusConflict
+	case ErrorCodeNotFound:
+		return http.StatusNotFound
+	case ErrorCodeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ExitCode returns the process exit code that corresponds to the error code.
+func (c ErrorCode) ExitCode() int {
+	switch c {
+	case ErrorCodeDecode:
+		return 2
+	case ErrorCodeInputValidation:
+		return 3
+	case ErrorCodeOption:
+		return 4
+	case ErrorCodeAlgorithm:
+		return 5
+	case ErrorCodeEncode:
+		return 6
+	case ErrorCodeTimeout:
+		return 7
+	default:
+		return 1
+	}
+}
+
+// ErrorDetail points to a single problem, e.g. a schema violation in the
+// input. The location is a JSON pointer.
+type ErrorDetail struct {
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+// Error is an error of a run together with its classification.
+type Error struct {
+	Code    ErrorCode
+	Err     error
+	Details []ErrorDetail
+}
+
+// NewError classifies err with the given code. If err is already classified,
+// it is returned as is. Details of validation errors are kept.
+func NewError(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	var runErr *Error
+	if errors.As(err, &runErr) {
+		return err
+	}
+	runErr = &Error{Code: code, Err: err}
+	var validationErr *validate.Error
+	if errors.As(err, &validationErr) {
+		for _, detail := range validationErr.Details {
+			runErr.Details = append(runErr.Details, ErrorDetail{
+				Location: detail.Location,
+				Message:  detail.Message,
+			})
+		}
+	}
+	return runErr
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON renders the error as {"error": {"code", "message", "details"}}.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorResponse{Error: errorBody{
+		Code:    e.Code,
+		Message: e.Err.Error(),
+		Details: e.Details,
+	}})
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    ErrorCode     `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// classify returns the classification of err. Unclassified errors have an
+// empty code.
+func classify(err error) *Error {
+	var runErr *Error
+	if errors.As(err, &runErr) {
+		return runErr
+	}
+	return &Error{Err: err}
+}
+
+// isSyntaxError reports whether err is caused by malformed JSON.
+func isSyntaxError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// ExitCode returns the exit code for the error a runner returned, so that
+// the failures of a CLI application can be told apart, e.g.:
+//
+//	if err := runner.Run(ctx); err != nil {
+//		log.Println(err)
+//		os.Exit(run.ExitCode(err))
+//	}
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return classify(err).Code.ExitCode()
+}
+
+// writeError writes the error as JSON with the HTTP status of its
+// classification. On an event stream, the error is sent as an error event
+// instead, since the stream may have started already.
+func writeError(w http.ResponseWriter, err error) error {
+	runErr := classify(err)
+	if w.Header().Get("Content-Type") == MediaTypeEventStream {
+		b, err := json.Marshal(runErr)
+		if err != nil {
+			return err
+		}
+		if err := writeEvent(w, "error", b); err != nil {
+			return err
+		}
+		return flush(w)
+	}
+	// errors are not compressed, even if the response was going to be.
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(runErr.Code.HTTPStatus())
+	return json.NewEncoder(w).Encode(runErr)
+}
@@ -0,0 +1,102 @@
+package run_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/runtest"
+	"github.com/nextmv-io/sdk/run/validate"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("unclassified"), 1},
+		{run.NewError(run.ErrorCodeDecode, errors.New("decode")), 2},
+		{fmt.Errorf("wrapped: %w",
+			run.NewError(run.ErrorCodeTimeout, errors.New("timeout"))), 7},
+	}
+	for _, test := range tests {
+		if got := run.ExitCode(test.err); got != test.want {
+			t.Errorf("got %v; want %v", got, test.want)
+		}
+	}
+}
+
+func TestNewErrorKeepsClassification(t *testing.T) {
+	err := run.NewError(run.ErrorCodeAlgorithm, errors.New("algorithm"))
+	err = run.NewError(run.ErrorCodeEncode, err)
+	var runErr *run.Error
+	if !errors.As(err, &runErr) || runErr.Code != run.ErrorCodeAlgorithm {
+		t.Errorf("got %v; want %v", runErr.Code, run.ErrorCodeAlgorithm)
+	}
+}
+
+func TestValidationErrorDetails(t *testing.T) {
+	type stop struct {
+		ID string `json:"id" required:"true"`
+	}
+	type input struct {
+		Stops []stop `json:"stops"`
+	}
+	validator := validate.JSON[input](nil)
+	err := validator(
+		context.Background(), strings.NewReader(`{"stops": [{"id": 1}, {}]}`),
+	)
+	err = run.NewError(run.ErrorCodeInputValidation, err)
+
+	b, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	var got struct {
+		Error struct {
+			Code    string            `json:"code"`
+			Details []run.ErrorDetail `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error.Code != string(run.ErrorCodeInputValidation) {
+		t.Errorf("got %v; want %v", got.Error.Code, run.ErrorCodeInputValidation)
+	}
+	locations := map[string]bool{}
+	for _, detail := range got.Error.Details {
+		locations[detail.Location] = true
+	}
+	for _, want := range []string{"/stops/0/id", "/stops/1/id"} {
+		if !locations[want] {
+			t.Errorf("got %v; want %v", got.Error.Details, want)
+		}
+	}
+}
+
+func TestSchemaErrorIsNotInputValidation(t *testing.T) {
+	type input struct {
+		Message string `json:"message"`
+	}
+	algorithm := func(_ context.Context, _ input, _ struct{}, _ chan<- any) error {
+		return nil
+	}
+	validator := validate.JSON[input]([]byte("not a schema"))
+	_, err := runtest.CLI(
+		context.Background(), algorithm, []byte(`{"message": "Hello"}`), nil,
+		run.InputValidate[run.CLIRunnerConfig, input, struct{}, any](validator),
+	)
+	var schemaErr *validate.SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Errorf("got %v; want %T", err, schemaErr)
+	}
+	if got := run.ExitCode(err); got != 1 {
+		t.Errorf("got %v; want %v", got, 1)
+	}
+}
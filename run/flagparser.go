@@ -1,21 +1,168 @@
 package run
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/itzg/go-flagsfiller"
+	"gopkg.in/yaml.v3"
 )
 
+// EnvPrefix is the prefix of the environment variables FlagParser reads, so
+// that the variables of different applications in a shared container do not
+// collide. For example, with the prefix "MYAPP" the input path is read from
+// MYAPP_RUNNER_INPUT_PATH instead of RUNNER_INPUT_PATH. Environment variables
+// named by an env tag are not prefixed. It must be set before the runner is
+// created. It is the default of CommandLine.EnvPrefix, see OSCommandLine.
+var EnvPrefix string
+
+// CommandLine holds the command line arguments and the environment variables
+// a runner is configured with. Runners constructed from an explicit command
+// line do not depend on the state of the process, so that several of them can
+// be constructed in one program or test.
+type CommandLine struct {
+	// Name is the name of the program in the usage message.
+	Name string
+	// Args are the command line arguments, without the program name.
+	Args []string
+	// Env are the environment variables in the form "key=value", as returned
+	// by os.Environ.
+	Env []string
+	// EnvPrefix is the prefix of the environment variables, see EnvPrefix.
+	EnvPrefix string
+	// Output receives the usage message and the errors of the flags. It is
+	// os.Stderr if nil.
+	Output io.Writer
+}
+
+// OSCommandLine returns the command line of the process: the arguments in
+// os.Args, the variables of os.Environ and the EnvPrefix.
+func OSCommandLine() CommandLine {
+	return CommandLine{
+		Name:      filepath.Base(os.Args[0]),
+		Args:      os.Args[1:],
+		Env:       os.Environ(),
+		EnvPrefix: EnvPrefix,
+	}
+}
+
+// lookupEnv returns the value of the environment variable with the given
+// name.
+func (c CommandLine) lookupEnv(name string) (string, bool) {
+	// the last value of a variable counts, like it does for os/exec.
+	for i := len(c.Env) - 1; i >= 0; i-- {
+		if key, value, ok := strings.Cut(c.Env[i], "="); ok && key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// flagError is an error of the flags of a command line, which the flag set
+// reports together with the usage message.
+type flagError struct {
+	err error
+}
+
+func (e flagError) Error() string {
+	return e.err.Error()
+}
+
+func (e flagError) Unwrap() error {
+	return e.err
+}
+
+// exitOnError exits the program if err is not nil. The usage message and the
+// errors of the flags are reported by the flag set already. As with the flag
+// package, asking for help exits with code 0 and invalid flags with code 2.
+func exitOnError(err error) {
+	var flagErr flagError
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case errors.As(err, &flagErr):
+		os.Exit(2)
+	}
+	log.Fatal(err)
+}
+
+// configFlag is the flag of the config file.
+const configFlag = "runner.config"
+
+// commander is implemented by runner configurations that take a command as
+// the first argument, before the flags.
+type commander interface {
+	setCommand(name string) error
+	commandUsage() string
+}
+
 // FlagParser parses flags and env vars and returns a runner config and options.
+// If the runner config takes a command, such as the CLIRunnerConfig, the first
+// argument is the command if it is not a flag.
+//
+// Both can also be read from a YAML, JSON or TOML config file, which is given
+// by the -runner.config flag. Its keys are the JSON names of the fields, e.g.:
+//
+//	runner:
+//	  output:
+//	    solutions: all
+//	duration: 10s
+//
+// Keys that match no field are an error. The precedence, from lowest to
+// highest, is: default values, the config file, environment variables and
+// flags. If help is asked for with -h or -help, the usage message is written
+// and flag.ErrHelp is returned.
 func FlagParser[Option, RunnerCfg any]() (
 	runnerConfig RunnerCfg, option Option, err error,
 ) {
-	// create a FlagSetFiller
+	return ParseCommandLine[Option, RunnerCfg](OSCommandLine())
+}
+
+// ParseCommandLine is FlagParser for the given command line.
+func ParseCommandLine[Option, RunnerCfg any](commandLine CommandLine) (
+	runnerConfig RunnerCfg, option Option, err error,
+) {
+	parsed, err := parseFlags[Option, RunnerCfg](commandLine)
+	return parsed.runnerConfig, parsed.option, err
+}
+
+// parsedFlags is the result of parseFlags.
+type parsedFlags[Option, RunnerCfg any] struct {
+	runnerConfig RunnerCfg
+	option       Option
+	// sources are the sources of the option fields that were set by the
+	// config file, environment variables or flags, by flag name.
+	sources map[string]OptionSource
+}
+
+// parseFlags parses the flags and the environment variables of a command
+// line and the config file, see FlagParser.
+func parseFlags[Option, RunnerCfg any](commandLine CommandLine) (
+	parsed parsedFlags[Option, RunnerCfg], err error,
+) {
+	flagSet := flag.NewFlagSet(commandLine.Name, flag.ContinueOnError)
+	if commandLine.Output != nil {
+		flagSet.SetOutput(commandLine.Output)
+	}
+	prefix := envPrefix(commandLine.EnvPrefix)
+	// create a FlagSetFiller. Environment variables are set below, so that
+	// they take precedence over the config file.
 	filler := flagsfiller.New(
-		flagsfiller.WithEnv(""),
+		flagsfiller.WithEnvRenamer(func(name string) string {
+			return envName(prefix, name)
+		}),
+		flagsfiller.NoSetFromEnv(),
 		flagsfiller.WithFieldRenamer(
 			func(name string) string {
 				repl := strings.ReplaceAll(name, "-", ".")
@@ -23,29 +170,255 @@ func FlagParser[Option, RunnerCfg any]() (
 			},
 		),
 	)
-	err = filler.Fill(flag.CommandLine, &option)
+	err = filler.Fill(flagSet, &parsed.option)
 	if err != nil {
-		return runnerConfig, option, err
+		return parsed, err
 	}
 
-	err = filler.Fill(flag.CommandLine, &runnerConfig)
+	err = filler.Fill(flagSet, &parsed.runnerConfig)
 	if err != nil {
-		return runnerConfig, option, err
+		return parsed, err
 	}
-	flag.Usage = usage
-	flag.Parse()
+	configEnv := envName(prefix, "Runner-Config")
+	configPath := flagSet.String(
+		configFlag,
+		"",
+		"The config file path (YAML, JSON or TOML), environment variables and "+
+			"flags take precedence (env "+configEnv+")",
+	)
 
-	return runnerConfig, option, nil
+	args := commandLine.Args
+	c, isCommander := any(&parsed.runnerConfig).(commander)
+	if isCommander && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if err := c.setCommand(args[0]); err != nil {
+			return parsed, err
+		}
+		args = args[1:]
+	}
+	flagSet.Usage = usage(flagSet, c)
+
+	fields := []fieldsOf{
+		{fields: optionFields(reflect.TypeOf(parsed.option)), value: &parsed.option, isOption: true},
+		{fields: optionFields(reflect.TypeOf(parsed.runnerConfig)), value: &parsed.runnerConfig},
+	}
+	parsed.sources = map[string]OptionSource{}
+	set := map[string]bool{}
+	err = setFromEnv(flagSet, commandLine, prefix, fields, set, parsed.sources)
+	if err != nil {
+		return parsed, err
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return parsed, flagError{err: err}
+	}
+	isOption := map[string]bool{}
+	for _, field := range fields[0].fields {
+		isOption[field.flag] = true
+	}
+	flagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if isOption[f.Name] {
+			parsed.sources[f.Name] = OptionSourceFlag
+		}
+	})
+
+	path := *configPath
+	if !set[configFlag] {
+		path, _ = commandLine.lookupEnv(configEnv)
+	}
+	if path == "" {
+		return parsed, nil
+	}
+	err = setFromConfig(path, fields, set, parsed.sources)
+	return parsed, err
+}
+
+// usage returns the usage function of the flag set. The commands are listed if
+// the runner config takes one.
+func usage(flagSet *flag.FlagSet, c commander) func() {
+	return func() {
+		out := flagSet.Output()
+		fmt.Fprint(
+			out,
+			"Nextmv Hybrid Optimization Platform\n",
+		)
+		if c != nil {
+			fmt.Fprintf(out, "Usage: %s [command] [flags]\n", flagSet.Name())
+			fmt.Fprint(out, c.commandUsage())
+			fmt.Fprint(out, "Flags:\n")
+		} else {
+			fmt.Fprint(out, "Usage:\n")
+		}
+		flagSet.PrintDefaults()
+	}
 }
 
-func usage() {
-	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	out := fs.Output()
+// setFromEnv sets the fields from their environment variables and records
+// them as set, and the sources of the option fields.
+func setFromEnv(
+	flagSet *flag.FlagSet,
+	commandLine CommandLine,
+	prefix string,
+	fields []fieldsOf,
+	set map[string]bool,
+	sources map[string]OptionSource,
+) error {
+	for _, f := range fields {
+		for _, field := range f.fields {
+			env := envFieldName(prefix, field)
+			value, ok := commandLine.lookupEnv(env)
+			flagValue := flagSet.Lookup(field.flag)
+			if !ok || flagValue == nil {
+				continue
+			}
+			if err := flagValue.Value.Set(value); err != nil {
+				return fmt.Errorf("failed to set from environment variable %s: %w", env, err)
+			}
+			set[field.flag] = true
+			if f.isOption {
+				sources[field.flag] = OptionSourceEnv
+			}
+		}
+	}
+	return nil
+}
 
-	fmt.Fprint(
-		out,
-		"Nextmv Hybrid Optimization Platform\n",
-	)
-	fmt.Fprint(out, "Usage:\n")
-	flag.PrintDefaults()
+// setFromConfig sets the fields that are not set yet from the config file at
+// path and records the sources of the option fields. An error is returned if
+// keys of the file match no field.
+func setFromConfig(
+	path string,
+	fields []fieldsOf,
+	set map[string]bool,
+	sources map[string]OptionSource,
+) error {
+	document, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	if unknown := unknownKeys(document, nil, fields); len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(unknown, ", "))
+	}
+	for _, f := range fields {
+		v := reflect.ValueOf(f.value).Elem()
+		for _, field := range f.fields {
+			value, ok := field.lookup(document)
+			if set[field.flag] || !ok {
+				continue
+			}
+			// the fields are set in place, since the flags point to them.
+			fieldValue, err := v.FieldByIndexErr(field.index)
+			if err != nil {
+				continue
+			}
+			if err := field.setValue(fieldValue, value); err != nil {
+				return fmt.Errorf("config file %s: %w", path, err)
+			}
+			if f.isOption {
+				sources[field.flag] = OptionSourceConfig
+			}
+		}
+	}
+	return nil
+}
+
+// unknownKeys returns the keys of the document, as dotted paths, that match
+// no field. The keys below path are matched like lookup matches them. The
+// values of fields, e.g. maps, are not looked into.
+func unknownKeys(document map[string]any, path []string, fields []fieldsOf) []string {
+	var unknown []string
+	for key, value := range document {
+		keys := append(path[:len(path):len(path)], key)
+		isField, isParent := false, false
+		for _, f := range fields {
+			for _, field := range f.fields {
+				if !hasKeys(field.keys, keys) {
+					continue
+				}
+				if len(field.keys) == len(keys) {
+					isField = true
+				} else {
+					isParent = true
+				}
+			}
+		}
+		m, isMap := value.(map[string]any)
+		switch {
+		case isField:
+		case isParent && isMap:
+			unknown = append(unknown, unknownKeys(m, keys, fields)...)
+		case !isParent:
+			unknown = append(unknown, strings.Join(keys, "."))
+		}
+	}
+	return unknown
+}
+
+// hasKeys reports whether the keys of a field start with the given keys,
+// ignoring case.
+func hasKeys(fieldKeys, keys []string) bool {
+	if len(keys) > len(fieldKeys) {
+		return false
+	}
+	for i, key := range keys {
+		if !strings.EqualFold(fieldKeys[i], key) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldsOf are the fields of the option or the runner config.
+type fieldsOf struct {
+	fields   []optionField
+	value    any
+	isOption bool
+}
+
+// envPrefix returns the prefix of the names of environment variables, which
+// ends with an underscore unless it is empty.
+func envPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return prefix
+}
+
+// envName returns the name of the environment variable of the field with the
+// given name, e.g. MYAPP_RUNNER_INPUT_PATH for Runner-Input-Path.
+func envName(prefix, name string) string {
+	return prefix + flagsfiller.ScreamingSnakeRenamer()(name)
+}
+
+// envFieldName returns the name of the environment variable of a field. The
+// name given by an env tag is not prefixed.
+func envFieldName(prefix string, field optionField) string {
+	if field.envTag {
+		return field.env
+	}
+	return prefix + field.env
+}
+
+// readConfig reads the config file at path. TOML files are recognized by the
+// .toml extension, other files are read as YAML, a superset of JSON.
+func readConfig(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var document map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		_, err = toml.NewDecoder(f).Decode(&document)
+	} else {
+		err = yaml.NewDecoder(f).Decode(&document)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return document, nil
 }
@@ -0,0 +1,91 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+type parserOptions struct {
+	Duration time.Duration `default:"1s"`
+	Name     string        `default:"world"`
+}
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		commandLine run.CommandLine
+		duration    time.Duration
+		name        string
+	}{
+		{run.CommandLine{}, time.Second, "world"},
+		{run.CommandLine{Args: []string{"-name", "flag"}}, time.Second, "flag"},
+		{run.CommandLine{Env: []string{"NAME=env", "DURATION=2s"}}, 2 * time.Second, "env"},
+		{
+			run.CommandLine{
+				Args:      []string{"-name", "flag"},
+				Env:       []string{"APP_NAME=env", "NAME=ignored", "APP_DURATION=3s"},
+				EnvPrefix: "APP",
+			},
+			3 * time.Second,
+			"flag",
+		},
+	}
+	for _, test := range tests {
+		_, option, err := run.ParseCommandLine[parserOptions, run.CLIRunnerConfig](test.commandLine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if option.Duration != test.duration {
+			t.Errorf("got %v; want %v", option.Duration, test.duration)
+		}
+		if option.Name != test.name {
+			t.Errorf("got %v; want %v", option.Name, test.name)
+		}
+	}
+}
+
+func TestParseCommandLineErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		help bool
+	}{
+		{[]string{"-h"}, true},
+		{[]string{"-unknown"}, false},
+		{[]string{"-duration", "forever"}, false},
+	}
+	for _, test := range tests {
+		commandLine := run.CommandLine{Args: test.args, Output: io.Discard}
+		_, _, err := run.ParseCommandLine[parserOptions, run.CLIRunnerConfig](commandLine)
+		if err == nil {
+			t.Errorf("got %v; want an error", err)
+		}
+		if got := errors.Is(err, flag.ErrHelp); got != test.help {
+			t.Errorf("got %v; want %v", got, test.help)
+		}
+	}
+}
+
+func TestNewCLIRunnerWith(t *testing.T) {
+	algorithm := func(_ context.Context, input any, _ parserOptions, solutions chan<- any) error {
+		solutions <- input
+		return nil
+	}
+	// runners can be constructed several times in one program.
+	for _, path := range []string{"first.json", "second.json"} {
+		runner, err := run.NewCLIRunnerWith(
+			run.CommandLine{Args: []string{"-runner.input.path", path}},
+			algorithm,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := runner.RunnerConfig().Runner.Input.Path; got != path {
+			t.Errorf("got %v; want %v", got, path)
+		}
+	}
+}
@@ -2,13 +2,13 @@ package run
 
 import (
 	"context"
-	"log"
-	"os"
+	"errors"
+	"io"
 	"reflect"
 	"runtime"
-	"runtime/pprof"
-	"sync"
 	"time"
+
+	"github.com/nextmv-io/sdk/run/validate"
 )
 
 type start string
@@ -17,10 +17,48 @@ type data string
 // Start is the key for the start time of the run.
 const Start start = "start"
 
-// Data is the key for additional data of the run.
+// Data is the key for additional data of the run, a *sync.Map. Use Set, Get
+// and Export to access it.
 const Data data = "data"
 
-// GenericRunner creates a new runner from the given components.
+// Phase is a phase of a run.
+type Phase string
+
+// Phases of a run whose durations can be observed with WithPhaseObserver.
+const (
+	// PhaseValidate is the validation of the input.
+	PhaseValidate Phase = "validate"
+	// PhaseDecode is the decoding of the input or the option.
+	PhaseDecode Phase = "decode"
+	// PhaseSolve is the execution of the algorithm.
+	PhaseSolve Phase = "solve"
+	// PhaseEncode is the encoding of the solutions. It overlaps with solve,
+	// since solutions are encoded while the algorithm produces them.
+	PhaseEncode Phase = "encode"
+)
+
+// PhaseObserver is called with the duration of each phase of a run.
+type PhaseObserver func(phase Phase, duration time.Duration)
+
+type phaseObserverKey struct{}
+
+// WithPhaseObserver returns a context that makes a runner report the duration
+// of the phases of a run to the given observer.
+func WithPhaseObserver(ctx context.Context, o PhaseObserver) context.Context {
+	return context.WithValue(ctx, phaseObserverKey{}, o)
+}
+
+// observePhase reports the duration of the phase that began at start to the
+// observer of the context, if there is one.
+func observePhase(ctx context.Context, phase Phase, start time.Time) {
+	if o, ok := ctx.Value(phaseObserverKey{}).(PhaseObserver); ok {
+		o(phase, time.Since(start))
+	}
+}
+
+// GenericRunner creates a new runner from the given components. It is
+// configured by the command line of the process and exits the program if it
+// cannot be parsed, see NewGenericRunner.
 func GenericRunner[RunnerConfig, Input, Option, Solution any](
 	ioHandler IOProducer[RunnerConfig],
 	inputDecoder Decoder[Input],
@@ -29,11 +67,63 @@ func GenericRunner[RunnerConfig, Input, Option, Solution any](
 	handler Algorithm[Input, Option, Solution],
 	encoder Encoder[Solution, Option],
 ) Runner[RunnerConfig, Input, Option, Solution] {
-	runnerConfig, option, err := FlagParser[
-		Option, RunnerConfig,
-	]()
+	runner, err := newGenericRunner(
+		OSCommandLine(),
+		ioHandler,
+		inputDecoder,
+		inputValidator,
+		optionDecoder,
+		handler,
+		encoder,
+	)
+	exitOnError(err)
+	return runner
+}
+
+// NewGenericRunner creates a new runner from the given components, which is
+// configured by the given command line. An error is returned if the command
+// line cannot be parsed, e.g. flag.ErrHelp if it asks for help.
+func NewGenericRunner[RunnerConfig, Input, Option, Solution any](
+	commandLine CommandLine,
+	ioHandler IOProducer[RunnerConfig],
+	inputDecoder Decoder[Input],
+	inputValidator Validator[Input],
+	optionDecoder Decoder[Option],
+	handler Algorithm[Input, Option, Solution],
+	encoder Encoder[Solution, Option],
+) (Runner[RunnerConfig, Input, Option, Solution], error) {
+	runner, err := newGenericRunner(
+		commandLine,
+		ioHandler,
+		inputDecoder,
+		inputValidator,
+		optionDecoder,
+		handler,
+		encoder,
+	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	return runner, nil
+}
+
+func newGenericRunner[RunnerConfig, Input, Option, Solution any](
+	commandLine CommandLine,
+	ioHandler IOProducer[RunnerConfig],
+	inputDecoder Decoder[Input],
+	inputValidator Validator[Input],
+	optionDecoder Decoder[Option],
+	handler Algorithm[Input, Option, Solution],
+	encoder Encoder[Solution, Option],
+) (*genericRunner[RunnerConfig, Input, Option, Solution], error) {
+	parsed, err := parseFlags[Option, RunnerConfig](commandLine)
+	if err != nil {
+		return nil, err
+	}
+	fields := optionFields(reflect.TypeOf(parsed.option))
+	constraints, err := optionConstraints(reflect.TypeOf(parsed.option), fields)
+	if err != nil {
+		return nil, err
 	}
 	return &genericRunner[RunnerConfig, Input, Option, Solution]{
 		IOProducer:       ioHandler,
@@ -42,9 +132,12 @@ func GenericRunner[RunnerConfig, Input, Option, Solution any](
 		OptionDecoder:    optionDecoder,
 		Algorithm:        handler,
 		Encoder:          encoder,
-		runnerConfig:     runnerConfig,
-		flagParsedOption: option,
-	}
+		runnerConfig:     parsed.runnerConfig,
+		flagParsedOption: parsed.option,
+		optionFields:     fields,
+		explicitOptions:  parsed.sources,
+		constraints:      constraints,
+	}, nil
 }
 
 type genericRunner[RunnerConfig, Input, Option, Solution any] struct {
@@ -56,152 +149,314 @@ type genericRunner[RunnerConfig, Input, Option, Solution any] struct {
 	Encoder          Encoder[Solution, Option]
 	runnerConfig     RunnerConfig
 	flagParsedOption Option
+	// optionFields are the fields of the option and explicitOptions the
+	// sources of the ones that were set by the config file, environment
+	// variables or flags.
+	optionFields    []optionField
+	explicitOptions map[string]OptionSource
+	// constraints are the constraints of the option fields, which the option
+	// of every run is checked against.
+	constraints []optionConstraint
 }
 
-func (r *genericRunner[RunnerConfig, Input, Option, Solution]) handleCPUProfile(
-	runnerConfig any,
-) (deferFunc func() error, err error) {
-	deferFunc = func() error {
-		return nil
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) handleTimeLimit(
+	ctx context.Context, runnerConfig any,
+) (context.Context, context.CancelFunc) {
+	if timeLimiter, ok := runnerConfig.(TimeLimiter); ok &&
+		timeLimiter.TimeLimit() > 0 {
+		return context.WithTimeout(ctx, timeLimiter.TimeLimit())
 	}
-	if cpuProfiler, ok := runnerConfig.(CPUProfiler); ok &&
-		cpuProfiler.CPUProfilePath() != "" {
-		// CPU profiler.
-		f, err := os.Create(cpuProfiler.CPUProfilePath())
-		if err != nil {
-			return deferFunc, err
-		}
-		deferFunc = func() error {
-			return f.Close()
-		}
+	return context.WithCancel(ctx)
+}
 
-		if err := pprof.StartCPUProfile(f); err != nil {
-			return deferFunc, err
-		}
-		deferFunc = func() error {
-			pprof.StopCPUProfile()
-			return f.Close()
+// codecs overrides the input decoder and the encoder of a runner for a single
+// run, e.g. as negotiated for an http request. The input validator is only
+// used with an overridden decoder if validate is set.
+type codecs[Input, Option, Solution any] struct {
+	decoder  Decoder[Input]
+	validate bool
+	encoder  Encoder[Solution, Option]
+}
+
+type codecsKey struct{}
+
+// withCodecs returns a context that makes a runner use the given codecs.
+func withCodecs[Input, Option, Solution any](
+	ctx context.Context, c codecs[Input, Option, Solution],
+) context.Context {
+	return context.WithValue(ctx, codecsKey{}, c)
+}
+
+// codecs returns the validator, decoder and encoder of a run. The codecs of
+// the context take precedence over the ones of the runner.
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) codecs(
+	ctx context.Context,
+) (Validator[Input], Decoder[Input], Encoder[Solution, Option]) {
+	validator, decoder, encoder := r.InputValidator, r.InputDecoder, r.Encoder
+	c, ok := ctx.Value(codecsKey{}).(codecs[Input, Option, Solution])
+	if !ok {
+		return validator, decoder, encoder
+	}
+	if c.decoder != nil {
+		decoder = c.decoder
+		if !c.validate {
+			validator = nil
 		}
 	}
-	return deferFunc, nil
+	if c.encoder != nil {
+		encoder = c.encoder
+	}
+	return validator, decoder, encoder
 }
 
-func (r *genericRunner[RunnerConfig, Input, Option, Solution],
-) handleMemoryProfile(runnerConfig any,
-) (deferFunc func() error, err error) {
-	deferFunc = func() error {
+// validateInput validates the input, if a validator is given.
+func validateInput[Input any](
+	ctx context.Context, ioData IOData, validator Validator[Input],
+) error {
+	if validator == nil {
 		return nil
 	}
-	// Memory profile.
-	if memoryProfiler, ok := runnerConfig.(MemoryProfiler); ok &&
-		memoryProfiler.MemoryProfilePath() != "" {
-		f, err := os.Create(memoryProfiler.MemoryProfilePath())
-		if err != nil {
-			return deferFunc, err
-		}
-		deferFunc = func() error {
-			return f.Close()
-		}
-
-		// Clean up unused objects from the heap before profiling. But do not
-		// garbage collect the runner, so we can see in-use memory.
-		runtime.GC()
-		runtime.KeepAlive(r)
+	validateStart := time.Now()
+	err := validator(ctx, ioData.Input())
+	observePhase(ctx, PhaseValidate, validateStart)
+	if err == nil {
+		return nil
+	}
+	// a schema that cannot be generated or loaded is not the fault of the
+	// input, it is left unclassified like other internal errors.
+	var schemaErr *validate.SchemaError
+	if errors.As(err, &schemaErr) {
+		return err
+	}
+	// input that cannot be parsed is reported as a decode error, even if the
+	// validator is the first to read it.
+	if isSyntaxError(err) {
+		return NewError(ErrorCodeDecode, err)
+	}
+	return NewError(ErrorCodeInputValidation, err)
+}
 
-		if err := pprof.WriteHeapProfile(f); err != nil {
-			return deferFunc, err
-		}
+// decodeInput validates the input, if a validator is given, and decodes it.
+func decodeInput[Input any](
+	ctx context.Context,
+	ioData IOData,
+	validator Validator[Input],
+	decoder Decoder[Input],
+) (input Input, err error) {
+	if err := validateInput(ctx, ioData, validator); err != nil {
+		return input, err
 	}
-	return deferFunc, nil
+
+	decodeStart := time.Now()
+	input, err = decoder(ctx, ioData.Input())
+	observePhase(ctx, PhaseDecode, decodeStart)
+	return input, NewError(ErrorCodeDecode, err)
 }
 
 func (r *genericRunner[RunnerConfig, Input, Option, Solution]) Run(
 	ctx context.Context,
+) error {
+	return r.profile(ctx, func(ctx context.Context) error {
+		return r.runWith(ctx, r.IOProducer)
+	})
+}
+
+// profile calls run while the profiles configured in the runner configuration
+// are recorded. The heap and allocation profiles are taken when the algorithm
+// returns, before the memory of the run is released.
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) profile(
+	ctx context.Context, run func(context.Context) error,
 ) (retErr error) {
-	start := time.Now()
-	ctx = context.WithValue(ctx, Start, start)
-	ctx = context.WithValue(ctx, Data, &sync.Map{})
-	// handle CPU profile
-	deferFuncCPU, retErr := r.handleCPUProfile(r.runnerConfig)
-	if retErr != nil {
-		return retErr
+	paths := configProfilePaths(r.runnerConfig)
+	if paths == (profilePaths{}) {
+		return run(ctx)
+	}
+	profiles, err := startProfiles(paths)
+	if err != nil {
+		return err
 	}
 	defer func() {
-		err := deferFuncCPU()
+		err := profiles.stop()
 		// the first error is more important
 		if retErr == nil {
 			retErr = err
 		}
 	}()
+	return run(withProfiles(ctx, profiles))
+}
+
+// runWith runs the algorithm once on the IO of the given producer. Unlike
+// SetIOProducer followed by Run, it does not modify the runner, so it can be
+// called concurrently, e.g. once per request or once per input of a batch.
+// Profiles are only recorded if the context carries them, since most of them
+// cover the process rather than a run.
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) runWith(
+	ctx context.Context, producer IOProducer[RunnerConfig],
+) error {
+	start := time.Now()
+	ctx = context.WithValue(ctx, Start, start)
+	ctx = withData(ctx)
+	ctx = withAuxiliaryInputs(ctx, r.runnerConfig)
+	// limit the duration of the run, the algorithm is expected to return its
+	// best solution so far once the context is done.
+	ctx, cancel := r.handleTimeLimit(ctx, r.runnerConfig)
+	defer cancel()
 	// get IO
-	ioData, retErr := r.IOProducer(ctx, r.runnerConfig)
-	if retErr != nil {
-		return retErr
+	ioData, err := producer(ctx, r.runnerConfig)
+	if err != nil {
+		return NewError(ErrorCodeDecode, err)
 	}
+	// the option decoder closes the source of the option once it is read, but
+	// it is not called if the input cannot be decoded.
+	defer closeOption(ioData)
 
-	if r.InputValidator != nil {
-		retErr = r.InputValidator(ctx, ioData.Input())
-		if retErr != nil {
-			return retErr
-		}
+	// validate and decode input
+	validator, decoder, encoder := r.codecs(ctx)
+	decodedInput, err := decodeInput(ctx, ioData, validator, decoder)
+	// the input is not needed anymore once it is decoded.
+	closeErr := closeIOData(ioData)
+	if err != nil {
+		return err
 	}
-
-	// decode input
-	decodedInput, retErr := r.InputDecoder(ctx, ioData.Input())
-	if retErr != nil {
-		return retErr
+	if closeErr != nil {
+		return NewError(ErrorCodeDecode, closeErr)
 	}
 
-	// use options configured in runner via flags and environment variables
-	decodedOption := r.flagParsedOption
-	// decode option if provided
-	tempOption, err := r.OptionDecoder(ctx, ioData.Option())
+	// decode option if provided and merge it with the options configured in
+	// the runner via flags and environment variables.
+	optionCtx, presence := withOptionPresence(ctx)
+	tempOption, err := r.OptionDecoder(optionCtx, ioData.Option())
 	if err != nil {
-		return err
+		return NewError(ErrorCodeOption, err)
 	}
-	var defaultOption Option
-	// if option is not default, use it
-	if !reflect.DeepEqual(tempOption, defaultOption) {
-		decodedOption = tempOption
+	decodedOption, sources := r.mergeOption(tempOption, presence)
+	ctx = context.WithValue(ctx, optionSourcesKey{}, sources)
+	err = validateOption(reflect.ValueOf(decodedOption), r.constraints)
+	if err != nil {
+		return NewError(ErrorCodeOption, err)
 	}
 
-	// run algorithm
-	solutions := make(chan Solution)
+	// run algorithm, the statistics of its solutions are collected on the way
+	// to the encoder.
+	ctx = withStatistics(ctx, start)
+	found := make(chan Solution)
+	solutions := collectStatistics(ctx, found)
 	errs := make(chan error, 1)
 	go func() {
-		defer close(solutions)
+		defer close(found)
 		defer close(errs)
-		retErr = r.Algorithm(ctx, decodedInput, decodedOption, solutions)
-		if retErr != nil {
-			errs <- retErr
+		solveStart := time.Now()
+		err := r.Algorithm(ctx, decodedInput, decodedOption, found)
+		observePhase(ctx, PhaseSolve, solveStart)
+		// the input is kept alive for the heap profile of the run.
+		algorithmReturned(ctx)
+		runtime.KeepAlive(decodedInput)
+		// An algorithm that stops because the time limit was reached is not
+		// considered to have failed. Its solutions are encoded as usual.
+		if errors.Is(err, context.DeadlineExceeded) &&
+			errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = nil
+		}
+		if err != nil {
+			code := ErrorCodeAlgorithm
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				code = ErrorCodeTimeout
+			}
+			errs <- NewError(code, err)
 			return
 		}
 	}()
 
 	// encode solutions
-	retErr = r.Encoder.Encode(
+	encodeStart := time.Now()
+	err = encoder.Encode(
 		ctx, solutions, ioData.Writer(), r.runnerConfig, decodedOption,
 	)
-	if retErr != nil {
-		return retErr
+	observePhase(ctx, PhaseEncode, encodeStart)
+	if err != nil {
+		// stop the algorithm and discard the solutions it still sends.
+		cancel()
+		go func() {
+			for range solutions {
+			}
+		}()
+		return NewError(ErrorCodeEncode, err)
 	}
 
-	// handle memory profile
-	deferFuncMemory, retErr := r.handleMemoryProfile(r.runnerConfig)
-	if retErr != nil {
-		return retErr
+	// return potential errors
+	return <-errs
+}
+
+// validate validates the input read from reader without decoding it or
+// running the algorithm.
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) validate(
+	ctx context.Context, reader io.Reader,
+) error {
+	ioData, err := NewIOData(reader, nil, io.Discard)
+	if err != nil {
+		return NewError(ErrorCodeDecode, err)
+	}
+	validator, _, _ := r.codecs(ctx)
+	err = validateInput(ctx, ioData, validator)
+	if closeErr := closeIOData(ioData); err == nil && closeErr != nil {
+		err = NewError(ErrorCodeDecode, closeErr)
 	}
+	return err
+}
 
-	defer func() {
-		err := deferFuncMemory()
-		// the first error is more important
-		if retErr == nil {
-			retErr = err
+// mergeOption merges a decoded option into the option configured via flags and
+// environment variables, field by field, and returns the sources of the merged
+// fields. The precedence, from lowest to highest, is: default values, the
+// config file, environment variables, flags, the options file and the
+// request. The fields the decoder recorded as set are taken from the decoded
+// option. If the decoder did not record any field, its non-zero fields are
+// taken.
+func (r *genericRunner[RunnerConfig, Input, Option, Solution]) mergeOption(
+	decoded Option, presence *optionPresence,
+) (Option, map[string]OptionSource) {
+	merged := r.flagParsedOption
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	decodedValue := reflect.ValueOf(&decoded).Elem()
+	sources := make(map[string]OptionSource, len(r.optionFields))
+	for _, field := range r.optionFields {
+		sources[field.flag] = OptionSourceDefault
+		if source, ok := r.explicitOptions[field.flag]; ok {
+			sources[field.flag] = source
 		}
-	}()
+		source, ok := presence.fields[field.flag]
+		if len(presence.fields) == 0 {
+			// a nil pointer on the way means that the field is not set.
+			value, err := decodedValue.FieldByIndexErr(field.index)
+			if err == nil && !value.IsZero() {
+				source, ok = OptionSourceDecoder, true
+			}
+		}
+		if ok {
+			field.field(mergedValue).Set(field.field(decodedValue))
+			sources[field.flag] = source
+		}
+	}
+	return merged, sources
+}
 
-	// return potential errors
-	return <-errs
+// ioRunner is implemented by runners that can run with a given IOProducer
+// without being modified.
+type ioRunner[RunnerConfig any] interface {
+	runWith(context.Context, IOProducer[RunnerConfig]) error
+}
+
+// runWith runs the runner once on the IO of the given producer. Runners that
+// cannot do so concurrently get the producer set before they are run.
+func runWith[RunnerConfig, Input, Option, Solution any](
+	ctx context.Context,
+	runner Runner[RunnerConfig, Input, Option, Solution],
+	producer IOProducer[RunnerConfig],
+) error {
+	if r, ok := runner.(ioRunner[RunnerConfig]); ok {
+		return r.runWith(ctx, producer)
+	}
+	runner.SetIOProducer(producer)
+	return runner.Run(ctx)
 }
 
 func (r *genericRunner[RunnerConfig, Input, Option, Solution]) SetIOProducer(
@@ -28,3 +28,23 @@ func Encode[Input, Option, Solution any, Encoder encode.Encoder](
 		run.GenericEncoder[Solution, Option](e),
 	)
 }
+
+// AddDecoder registers a decoder of a HTTPRunner for the given media type. The
+// decoder of a request is chosen by its Content-Type header.
+func AddDecoder[Input, Option, Solution any, Decoder decode.Decoder](
+	mediaType string, d Decoder,
+) run.HTTPRunnerOption[Input, Option, Solution] {
+	return run.AddDecoder[Input, Option, Solution](
+		mediaType, run.GenericDecoder[Input](d),
+	)
+}
+
+// AddEncoder registers an encoder of a HTTPRunner for the given media type.
+// The encoder of a request is chosen by its Accept header.
+func AddEncoder[Input, Option, Solution any, Encoder encode.Encoder](
+	mediaType string, e Encoder,
+) run.HTTPRunnerOption[Input, Option, Solution] {
+	return run.AddEncoder[Input](
+		mediaType, run.GenericEncoder[Solution, Option](e),
+	)
+}
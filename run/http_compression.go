@@ -0,0 +1,141 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// checkContentEncoding returns an error if the Content-Encoding of a request
+// is not supported. Supported request bodies are decompressed by their magic
+// bytes, so the header is only checked, not used.
+func checkContentEncoding(req *http.Request) error {
+	for _, encoding := range strings.Split(req.Header.Get("Content-Encoding"), ",") {
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "", "identity", "gzip", "x-gzip", "zstd", "bzip2", "x-bzip2":
+		default:
+			return fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+	}
+	return nil
+}
+
+// acceptEncoding returns the compression of a response with the given
+// Accept-Encoding header. Of the encodings with the highest quality, zstd is
+// preferred over gzip.
+func acceptEncoding(header string) Compression {
+	compression, best := CompressionNone, 0.0
+	for _, part := range strings.Split(header, ",") {
+		encoding, params, _ := strings.Cut(part, ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		var c Compression
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "zstd", "*":
+			c = CompressionZstd
+		case "gzip", "x-gzip":
+			c = CompressionGzip
+		default:
+			continue
+		}
+		if quality > best || quality == best && quality > 0 && c == CompressionZstd {
+			compression, best = c, quality
+		}
+	}
+	return compression
+}
+
+// prepareEncoding compresses the response of a synchronous run as negotiated
+// by the Accept-Encoding header of the request. Event streams are not
+// compressed, so that every event reaches the client as soon as it is sent.
+func (h *httpRunner[Input, Option, Solution]) prepareEncoding(
+	w http.ResponseWriter,
+	req *http.Request,
+	producer IOProducer[HTTPRunnerConfig],
+) IOProducer[HTTPRunnerConfig] {
+	if w.Header().Get("Content-Type") == MediaTypeEventStream {
+		return producer
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	compression := acceptEncoding(req.Header.Get("Accept-Encoding"))
+	if compression == CompressionNone {
+		return producer
+	}
+	w.Header().Set("Content-Encoding", string(compression))
+	return compressIOProducer(producer, compression)
+}
+
+// compressIOProducer wraps an IOProducer so that its output is compressed.
+func compressIOProducer[RunnerConfig any](
+	producer IOProducer[RunnerConfig], compression Compression,
+) IOProducer[RunnerConfig] {
+	return func(ctx context.Context, cfg RunnerConfig) (IOData, error) {
+		data, err := producer(ctx, cfg)
+		if err != nil {
+			return data, err
+		}
+		writer, ok := data.Writer().(io.Writer)
+		if !ok {
+			return data, nil
+		}
+		compressor, err := newCompressWriter(writer, compression)
+		if err != nil {
+			return data, err
+		}
+		return compressIOData{
+			IOData: data,
+			writer: compressedWriter{compressor: compressor, writer: writer},
+		}, nil
+	}
+}
+
+type compressIOData struct {
+	IOData
+	writer compressedWriter
+}
+
+func (d compressIOData) Writer() any {
+	return d.writer
+}
+
+// Close closes the wrapped IOData, if it can be closed.
+func (d compressIOData) Close() error {
+	return closeIOData(d.IOData)
+}
+
+// compressedWriter compresses everything written to it and writes it to
+// writer. Closing it ends the compressed stream and closes writer.
+type compressedWriter struct {
+	compressor compressWriter
+	writer     io.Writer
+}
+
+func (c compressedWriter) Write(p []byte) (int, error) {
+	return c.compressor.Write(p)
+}
+
+// Flush writes all pending data and flushes the underlying writer.
+func (c compressedWriter) Flush() error {
+	if err := c.compressor.Flush(); err != nil {
+		return err
+	}
+	return flush(c.writer)
+}
+
+func (c compressedWriter) Close() error {
+	err := c.compressor.Close()
+	if closer, ok := c.writer.(io.Closer); ok {
+		if tempErr := closer.Close(); err == nil {
+			err = tempErr
+		}
+	}
+	return err
+}
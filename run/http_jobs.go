@@ -0,0 +1,157 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// runsPath is the path under which the status and result of asynchronous runs
+// can be polled: GET /runs/{id} and GET /runs/{id}/result.
+const runsPath = "/runs/"
+
+// serveJob serves the status or the result of an asynchronous run.
+func (h *httpRunner[Input, Option, Solution]) serveJob(
+	w http.ResponseWriter, req *http.Request,
+) {
+	id, resource, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, runsPath), "/")
+	if id == "" || (resource != "" && resource != "result") {
+		h.reject(w, NewError(ErrorCodeNotFound, fmt.Errorf("path %s not found", req.URL.Path)))
+		return
+	}
+
+	job, err := h.jobStore.Job(req.Context(), id)
+	if errors.Is(err, ErrJobNotFound) {
+		h.reject(w, NewError(ErrorCodeNotFound, fmt.Errorf("run %s not found", id)))
+		return
+	}
+	if err != nil {
+		handleError(h.httpServer.ErrorLog, false, err, w)
+		return
+	}
+
+	if resource == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(job); err != nil {
+			h.httpServer.ErrorLog.Println(err)
+		}
+		return
+	}
+
+	if job.Status != JobSucceeded {
+		h.reject(w, NewError(ErrorCodeNoResult,
+			fmt.Errorf("run %s has no result, its status is %s", id, job.Status),
+		))
+		return
+	}
+	result, err := h.jobStore.Result(req.Context(), id)
+	if err != nil {
+		handleError(h.httpServer.ErrorLog, false, err, w)
+		return
+	}
+	w.Header().Set("Content-Type", job.ContentType)
+	if _, err := w.Write(result); err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// saveJob saves the job in the job store. Failing to do so does not fail the
+// run, so the error is only logged.
+func (h *httpRunner[Input, Option, Solution]) saveJob(
+	ctx context.Context, job Job,
+) {
+	if err := h.jobStore.SaveJob(ctx, job); err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// startJob marks the job as running.
+func (h *httpRunner[Input, Option, Solution]) startJob(
+	ctx context.Context, job Job,
+) Job {
+	now := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	h.saveJob(ctx, job)
+	return job
+}
+
+// finishJob stores the result of the job and marks it as succeeded or failed,
+// depending on the error the run returned.
+func (h *httpRunner[Input, Option, Solution]) finishJob(
+	ctx context.Context, job Job, result []byte, runErr error,
+) Job {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = JobSucceeded
+	if runErr != nil {
+		job.Status = JobFailed
+		job.Error = runErr.Error()
+	} else if err := h.jobStore.SaveResult(ctx, job.ID, result); err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	h.saveJob(ctx, job)
+	return job
+}
+
+// teeIOProducer wraps an IOProducer so that everything written to the output
+// is also written to tee.
+func teeIOProducer[RunnerConfig any](
+	producer IOProducer[RunnerConfig], tee io.Writer,
+) IOProducer[RunnerConfig] {
+	return func(ctx context.Context, cfg RunnerConfig) (IOData, error) {
+		data, err := producer(ctx, cfg)
+		if err != nil {
+			return data, err
+		}
+		writer, ok := data.Writer().(io.Writer)
+		if !ok {
+			return data, nil
+		}
+		return teeIOData{
+			IOData: data,
+			writer: teeWriter{writer: writer, tee: tee},
+		}, nil
+	}
+}
+
+type teeIOData struct {
+	IOData
+	writer teeWriter
+}
+
+func (d teeIOData) Writer() any {
+	return d.writer
+}
+
+// Close closes the wrapped IOData, if it can be closed.
+func (d teeIOData) Close() error {
+	return closeIOData(d.IOData)
+}
+
+// teeWriter writes to writer and tee. Closing it only closes writer.
+type teeWriter struct {
+	writer io.Writer
+	tee    io.Writer
+}
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	n, err := t.writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return t.tee.Write(p)
+}
+
+func (t teeWriter) Close() error {
+	if closer, ok := t.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
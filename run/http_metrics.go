@@ -0,0 +1,236 @@
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsPath is the path of the metrics endpoint.
+const metricsPath = "/metrics"
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the
+// phase duration histograms. They reach further than usual for http services,
+// since solving often takes minutes.
+var durationBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 600,
+}
+
+// httpMetrics collects the metrics of an HTTPRunner. They are exposed in the
+// Prometheus text exposition format.
+type httpMetrics struct {
+	mutex     sync.Mutex
+	requests  map[[2]string]uint64
+	phases    map[Phase]*histogram
+	callbacks map[string]uint64
+}
+
+func newHTTPMetrics() *httpMetrics {
+	phases := map[Phase]*histogram{}
+	for _, phase := range []Phase{
+		PhaseValidate, PhaseDecode, PhaseSolve, PhaseEncode,
+	} {
+		phases[phase] = newHistogram(durationBuckets)
+	}
+	return &httpMetrics{
+		requests:  map[[2]string]uint64{},
+		phases:    phases,
+		callbacks: map[string]uint64{},
+	}
+}
+
+// observeRequest counts a request to the given endpoint that was answered
+// with the given status code.
+func (m *httpMetrics) observeRequest(endpoint string, code int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests[[2]string{endpoint, strconv.Itoa(code)}]++
+}
+
+// observePhase is a PhaseObserver.
+func (m *httpMetrics) observePhase(phase Phase, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if h, ok := m.phases[phase]; ok {
+		h.observe(duration.Seconds())
+	}
+}
+
+// observeCallback counts a callback of an asynchronous run.
+func (m *httpMetrics) observeCallback(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err != nil {
+		m.callbacks["failure"]++
+		return
+	}
+	m.callbacks["success"]++
+}
+
+// write writes the metrics in the Prometheus text exposition format. Gauges
+// are passed in, since they are read from the runner when scraped.
+func (m *httpMetrics) write(w io.Writer, activeRuns, queuedRuns int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b := bufio.NewWriter(w)
+	writeHeader(b, "nextmv_http_requests_total", "counter",
+		"Number of http requests by endpoint and status code.")
+	keys := make([][2]string, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(b, "nextmv_http_requests_total{endpoint=%q,code=%q} %d\n",
+			key[0], key[1], m.requests[key])
+	}
+
+	writeHeader(b, "nextmv_run_phase_duration_seconds", "histogram",
+		"Duration of the phases of a run.")
+	for _, phase := range []Phase{
+		PhaseValidate, PhaseDecode, PhaseSolve, PhaseEncode,
+	} {
+		m.phases[phase].write(
+			b, "nextmv_run_phase_duration_seconds", fmt.Sprintf("phase=%q", phase),
+		)
+	}
+
+	writeHeader(b, "nextmv_active_runs", "gauge",
+		"Number of runs in progress.")
+	fmt.Fprintf(b, "nextmv_active_runs %d\n", activeRuns)
+	writeHeader(b, "nextmv_queued_runs", "gauge",
+		"Number of requests waiting for a free slot.")
+	fmt.Fprintf(b, "nextmv_queued_runs %d\n", queuedRuns)
+
+	writeHeader(b, "nextmv_async_callbacks_total", "counter",
+		"Number of callbacks of asynchronous runs by result.")
+	for _, result := range []string{"failure", "success"} {
+		fmt.Fprintf(b, "nextmv_async_callbacks_total{result=%q} %d\n",
+			result, m.callbacks[result])
+	}
+	return b.Flush()
+}
+
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// histogram is a Prometheus histogram with cumulative buckets.
+type histogram struct {
+	upperBounds []float64
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func newHistogram(upperBounds []float64) *histogram {
+	return &histogram{
+		upperBounds: upperBounds,
+		counts:      make([]uint64, len(upperBounds)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upperBound := range h.upperBounds {
+		if v <= upperBound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	for i, upperBound := range h.upperBounds {
+		fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n",
+			name, labels, formatFloat(upperBound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// serveMetrics serves the metrics of the runner.
+func (h *httpRunner[Input, Option, Solution]) serveMetrics(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	err := h.metrics.write(w, h.ActiveRuns(), h.QueuedRuns())
+	if err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// endpoint returns the name of the endpoint a request path belongs to. It is
+// used as a label, so it must not contain request specific parts, such as the
+// id of a run.
+func endpoint(path string) string {
+	switch {
+	case path == solvePath:
+		return "run"
+	case path == healthPath:
+		return "health"
+	case path == readyPath:
+		return "ready"
+	case path == infoPath:
+		return "info"
+	case path == optionsPath:
+		return "options"
+	case path == metricsPath:
+		return "metrics"
+	case path == openAPIPath:
+		return "openapi"
+	case strings.HasPrefix(path, runsPath):
+		return "runs"
+	default:
+		return "unknown"
+	}
+}
+
+// statusRecorder records the status code written to a http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, so that streamed responses keep working.
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to access the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,47 @@
+package run
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	m := newHTTPMetrics()
+	m.observeRequest("run", 200)
+	m.observeRequest("run", 200)
+	m.observeRequest("run", 429)
+	m.observePhase(PhaseSolve, 2*time.Second)
+	m.observePhase(PhaseSolve, 20*time.Millisecond)
+	m.observeCallback(nil)
+	m.observeCallback(errors.New("unreachable"))
+
+	var b bytes.Buffer
+	if err := m.write(&b, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"# TYPE nextmv_http_requests_total counter\n",
+		`nextmv_http_requests_total{endpoint="run",code="200"} 2` + "\n",
+		`nextmv_http_requests_total{endpoint="run",code="429"} 1` + "\n",
+		"# TYPE nextmv_run_phase_duration_seconds histogram\n",
+		`nextmv_run_phase_duration_seconds_bucket{phase="solve",le="0.01"} 0` + "\n",
+		`nextmv_run_phase_duration_seconds_bucket{phase="solve",le="0.025"} 1` + "\n",
+		`nextmv_run_phase_duration_seconds_bucket{phase="solve",le="2.5"} 2` + "\n",
+		`nextmv_run_phase_duration_seconds_bucket{phase="solve",le="+Inf"} 2` + "\n",
+		`nextmv_run_phase_duration_seconds_sum{phase="solve"} 2.02` + "\n",
+		`nextmv_run_phase_duration_seconds_count{phase="solve"} 2` + "\n",
+		`nextmv_run_phase_duration_seconds_count{phase="encode"} 0` + "\n",
+		"nextmv_active_runs 1\n",
+		"nextmv_queued_runs 2\n",
+		`nextmv_async_callbacks_total{result="failure"} 1` + "\n",
+		`nextmv_async_callbacks_total{result="success"} 1` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got\n%s\nwant it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package run
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMediaType is the media type of the default decoder and encoder of an
+// HTTPRunner.
+const defaultMediaType = "application/json"
+
+// AddDecoder registers an input decoder for the given media type. Once a
+// decoder is registered, the decoder of a request is chosen by its
+// Content-Type header. Requests without a Content-Type header, or with the
+// one of the default decoder, use the default decoder. Other requests are
+// rejected with 415 Unsupported Media Type. The input validator is only used
+// for JSON media types.
+func AddDecoder[Input, Option, Solution any](
+	mediaType string, decoder Decoder[Input],
+) func(*httpRunner[Input, Option, Solution]) {
+	return func(r *httpRunner[Input, Option, Solution]) {
+		r.addDecoder(mediaType, decoder)
+	}
+}
+
+// AddEncoder registers an encoder for the given media type. Once an encoder is
+// registered, the encoder of a request is chosen by its Accept header. Requests
+// without an Accept header use the default encoder. Requests that accept none
+// of the media types are rejected with 406 Not Acceptable.
+func AddEncoder[Input, Option, Solution any](
+	mediaType string, encoder Encoder[Solution, Option],
+) func(*httpRunner[Input, Option, Solution]) {
+	return func(r *httpRunner[Input, Option, Solution]) {
+		r.addEncoder(mediaType, encoder)
+	}
+}
+
+func (h *httpRunner[Input, Option, Solution]) addDecoder(
+	mediaType string, decoder Decoder[Input],
+) {
+	h.decoders[strings.ToLower(mediaType)] = decoder
+}
+
+func (h *httpRunner[Input, Option, Solution]) addEncoder(
+	mediaType string, encoder Encoder[Solution, Option],
+) {
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := h.encoders[mediaType]; !ok {
+		h.encoderTypes = append(h.encoderTypes, mediaType)
+	}
+	h.encoders[mediaType] = encoder
+}
+
+// negotiate chooses the decoder and the encoder of a request. It returns the
+// codecs of the run and the content type of the response. The default codecs
+// of the runner are left unset. On failure, the error is classified as
+// ErrorCodeUnsupportedMediaType or ErrorCodeNotAcceptable. Requests with an
+// unsupported Content-Encoding are rejected as unsupported media types.
+func (h *httpRunner[Input, Option, Solution]) negotiate(
+	req *http.Request, defaultContentType string,
+) (codecs[Input, Option, Solution], string, error) {
+	var c codecs[Input, Option, Solution]
+	if err := checkContentEncoding(req); err != nil {
+		return c, "", NewError(ErrorCodeUnsupportedMediaType, err)
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" &&
+		len(h.decoders) > 0 {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		decoder, ok := h.decoders[mediaType]
+		switch {
+		case err == nil && ok:
+			c.decoder = decoder
+			c.validate = isJSON(mediaType)
+		case err != nil || mediaType != defaultMediaType:
+			return c, "", NewError(ErrorCodeUnsupportedMediaType,
+				fmt.Errorf("unsupported content type %q", contentType))
+		}
+	}
+
+	accept := req.Header.Get("Accept")
+	if accept == "" || len(h.encoders) == 0 {
+		return c, defaultContentType, nil
+	}
+	// the default encoder comes first, so that it wins for wildcards.
+	defaultType, _, _ := mime.ParseMediaType(defaultContentType)
+	mediaTypes := append([]string{defaultType}, h.encoderTypes...)
+	for _, accepted := range parseAccept(accept) {
+		for _, mediaType := range mediaTypes {
+			if !accepts(accepted, mediaType) {
+				continue
+			}
+			if encoder, ok := h.encoders[mediaType]; ok {
+				c.encoder = encoder
+				return c, mediaType, nil
+			}
+			return c, defaultContentType, nil
+		}
+	}
+	return c, "", NewError(ErrorCodeNotAcceptable,
+		fmt.Errorf("none of the accepted media types %q is supported", accept))
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by their
+// quality, highest first. Ranges with a quality of 0 are left out.
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType, quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	mediaTypes := make([]string, len(ranges))
+	for i, r := range ranges {
+		mediaTypes[i] = r.mediaType
+	}
+	return mediaTypes
+}
+
+// accepts reports whether the media range of an Accept header matches the
+// media type. Streamed responses are accepted from JSON encoders.
+func accepts(mediaRange, mediaType string) bool {
+	switch {
+	case mediaRange == "*/*" || mediaRange == mediaType:
+		return true
+	case mediaRange == MediaTypeEventStream || mediaRange == MediaTypeNDJSON:
+		return mediaType == defaultMediaType
+	case strings.HasSuffix(mediaRange, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+// isJSON reports whether the media type is JSON, e.g. application/json or
+// application/problem+json.
+func isJSON(mediaType string) bool {
+	return mediaType == defaultMediaType || strings.HasSuffix(mediaType, "+json")
+}
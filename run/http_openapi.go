@@ -0,0 +1,343 @@
+package run
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	humaSchema "github.com/danielgtaylor/huma/schema"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+// openAPIPath is the path of the OpenAPI document of the HTTPRunner.
+const openAPIPath = "/openapi.json"
+
+// object is a JSON object of the OpenAPI document. The document is built from
+// maps rather than structs, since its keys are camel case.
+type object = map[string]any
+
+// serveOpenAPI responds with the OpenAPI 3 document of the endpoints that
+// start runs and poll for their results.
+func (h *httpRunner[Input, Option, Solution]) serveOpenAPI(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	document, err := h.openAPI()
+	if err != nil {
+		handleError(h.httpServer.ErrorLog, false, err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// openAPI returns the OpenAPI document of the runner. The schemas of the input
+// and the solution are generated from their types, the options are described
+// as the query parameters QueryParamDecoder reads. Runs respond with the
+// solutions if they are synchronous and with the id of the run if they are
+// asynchronous, in which case the solutions are sent to the callback url.
+func (h *httpRunner[Input, Option, Solution]) openAPI() (object, error) {
+	schemas := object{}
+	for name, v := range map[string]any{
+		"Input":    new(Input),
+		"Solution": new(Solution),
+		"Job":      new(Job),
+		"Error":    new(errorResponse),
+	} {
+		s, err := humaSchema.Generate(reflect.TypeOf(v))
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = s
+	}
+	parameters, err := optionParameters(reflect.TypeOf(new(Option)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	parameters = append(parameters,
+		object{
+			"name": "callback_url", "in": "header",
+			"description": "The url the solutions of an asynchronous run are posted to.",
+			"schema":      object{"type": "string", "format": "uri"},
+		},
+		object{
+			"name": ProfileHeader, "in": "header",
+			"description": "The profiles to record for the run, e.g. cpu,heap, " +
+				"if the runner has a profile directory.",
+			"schema": object{"type": "string"},
+		},
+	)
+
+	requestTypes := []string{defaultMediaType}
+	for mediaType := range h.decoders {
+		if mediaType != defaultMediaType {
+			requestTypes = append(requestTypes, mediaType)
+		}
+	}
+	sort.Strings(requestTypes[1:])
+	responseTypes := []string{defaultMediaType}
+	if contentTyper, ok := h.Runner.GetEncoder().(ContentTyper); ok {
+		responseTypes[0], _, _ = strings.Cut(contentTyper.ContentType(), ";")
+	}
+	for _, mediaType := range h.encoderTypes {
+		if mediaType != responseTypes[0] {
+			responseTypes = append(responseTypes, mediaType)
+		}
+	}
+	solutions := content(responseTypes, "Solution")
+	if isJSON(responseTypes[0]) {
+		solutions[MediaTypeNDJSON] = object{}
+		solutions[MediaTypeEventStream] = object{}
+	}
+	solutions["text/plain"] = object{"schema": object{"type": "string"}}
+	runResponse := object{
+		"description": "The solutions of a synchronous run or, as text/plain, " +
+			"the id of an asynchronous run.",
+		"headers": object{
+			"Location": object{
+				"description": "The path of the asynchronous run.",
+				"schema":      object{"type": "string"},
+			},
+		},
+		"content": solutions,
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "Nextmv HTTPRunner",
+			"version": openAPIVersion(),
+		},
+		"paths": object{
+			solvePath: object{
+				"post": object{
+					"operationId": "run",
+					"summary":     "Run the algorithm on the input",
+					"parameters":  parameters,
+					"requestBody": object{
+						"required": true,
+						"content":  content(requestTypes, "Input"),
+					},
+					"responses": errorResponses(object{"200": runResponse},
+						http.StatusBadRequest, http.StatusNotAcceptable,
+						http.StatusConflict, http.StatusUnsupportedMediaType,
+						http.StatusUnprocessableEntity, http.StatusTooManyRequests,
+						http.StatusInternalServerError, http.StatusServiceUnavailable,
+						http.StatusGatewayTimeout,
+					),
+					"callbacks": object{
+						"result": object{
+							"{$request.header.callback_url}": object{
+								"post": callbackOperation(responseTypes),
+							},
+						},
+					},
+				},
+			},
+			runsPath + "{id}": object{
+				"get": object{
+					"operationId": "getRun",
+					"summary":     "Get the status of an asynchronous run",
+					"parameters":  []object{runIDParameter()},
+					"responses": errorResponses(object{
+						"200": object{
+							"description": "The status of the run.",
+							"content":     content([]string{defaultMediaType}, "Job"),
+						},
+					}, http.StatusNotFound, http.StatusInternalServerError),
+				},
+			},
+			runsPath + "{id}/result": object{
+				"get": object{
+					"operationId": "getRunResult",
+					"summary":     "Get the solutions of a succeeded asynchronous run",
+					"parameters":  []object{runIDParameter()},
+					"responses": errorResponses(object{
+						"200": object{
+							"description": "The solutions of the run.",
+							"content":     content(responseTypes, "Solution"),
+						},
+					}, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+				},
+			},
+		},
+		"components": object{"schemas": schemas},
+	}, nil
+}
+
+// optionParameters returns the query parameters of the fields of the option
+// type t.
+func optionParameters(t reflect.Type) ([]object, error) {
+	fields := optionFields(t)
+	constraints, err := optionConstraints(t, fields)
+	if err != nil {
+		return nil, err
+	}
+	constraintsByFlag := byFlag(constraints)
+	parameters := make([]object, 0, len(fields))
+	for _, field := range fields {
+		structField := structFieldByIndex(t, field.index)
+		isDuration := structField.Type == durationType
+		structField.Type, _ = optionSchemaType(structField.Type)
+		_, _, s, err := humaSchema.GenerateFromField(
+			structField,
+			humaSchema.ModeAll,
+			map[string]humaSchema.NestedSchemaReference{},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if isDuration {
+			// QueryParamDecoder reads durations as nanoseconds.
+			s.Type, s.Format = humaSchema.TypeInteger, "int64"
+			if d, ok := s.Default.(string); ok {
+				duration, err := time.ParseDuration(d)
+				if err != nil {
+					return nil, err
+				}
+				s.Default = duration.Nanoseconds()
+			}
+		}
+		description := s.Description
+		if description == "" {
+			description = structField.Tag.Get("usage")
+		}
+		parameter := object{
+			"name":        field.queryKey(t),
+			"in":          "query",
+			"description": description,
+			"schema":      s,
+		}
+		if constraint, ok := constraintsByFlag[field.flag]; ok {
+			constrainSchema(s, constraint)
+			if constraint.required {
+				parameter["required"] = true
+			}
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters, nil
+}
+
+// constrainSchema adds the min, max and enum constraints of an option field to
+// its schema.
+func constrainSchema(s *humaSchema.Schema, constraint optionConstraint) {
+	length := func(bound *float64) *uint64 {
+		if bound == nil {
+			return nil
+		}
+		return humaSchema.I(uint64(*bound))
+	}
+	switch s.Type {
+	case humaSchema.TypeString:
+		s.MinLength, s.MaxLength = length(constraint.min), length(constraint.max)
+	case humaSchema.TypeArray:
+		s.MinItems, s.MaxItems = length(constraint.min), length(constraint.max)
+	case humaSchema.TypeObject:
+		s.MinProperties, s.MaxProperties = length(constraint.min), length(constraint.max)
+	default:
+		s.Minimum, s.Maximum = constraint.min, constraint.max
+	}
+	if len(constraint.enum) == 0 {
+		return
+	}
+	if s.Type == humaSchema.TypeArray && s.Items != nil {
+		s.Items.Enum = constraint.enum
+		return
+	}
+	s.Enum = constraint.enum
+}
+
+// structFieldByIndex returns the nested field of the struct type t with the
+// given index path. Unlike reflect.Type.FieldByIndex, it follows pointers to
+// structs that are not embedded.
+func structFieldByIndex(t reflect.Type, index []int) reflect.StructField {
+	var field reflect.StructField
+	for _, i := range index {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		field = t.Field(i)
+		t = field.Type
+	}
+	return field
+}
+
+// callbackOperation returns the request the solutions of an asynchronous run
+// are posted with to the callback url.
+func callbackOperation(mediaTypes []string) object {
+	return object{
+		"parameters": []object{
+			{
+				"name": "request_id", "in": "header", "required": true,
+				"description": "The id of the run.",
+				"schema":      object{"type": "string"},
+			},
+			{
+				"name": CallbackSignatureHeader, "in": "header",
+				"description": "The HMAC-SHA256 signature of the body, " +
+					"if a callback secret is configured.",
+				"schema": object{"type": "string"},
+			},
+		},
+		"requestBody": object{
+			"required": true,
+			"content":  content(mediaTypes, "Solution"),
+		},
+		"responses": object{
+			"200": object{"description": "The solutions were received."},
+		},
+	}
+}
+
+// runIDParameter is the path parameter of the id of an asynchronous run.
+func runIDParameter() object {
+	return object{
+		"name": "id", "in": "path", "required": true,
+		"schema": object{"type": "string"},
+	}
+}
+
+// content returns the content of a request or response body in the given
+// media types. The JSON media types refer to the named schema.
+func content(mediaTypes []string, schemaName string) object {
+	c := object{}
+	for _, mediaType := range mediaTypes {
+		if !isJSON(mediaType) {
+			c[mediaType] = object{}
+			continue
+		}
+		c[mediaType] = object{
+			"schema": object{"$ref": "#/components/schemas/" + schemaName},
+		}
+	}
+	return c
+}
+
+// errorResponses adds the responses of the given error statuses, whose body
+// is a JSON Error.
+func errorResponses(responses object, statuses ...int) object {
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = object{
+			"description": http.StatusText(status),
+			"content":     content([]string{defaultMediaType}, "Error"),
+		}
+	}
+	return responses
+}
+
+// openAPIVersion returns the version of the sdk as the version of the
+// document.
+func openAPIVersion() string {
+	if version := schema.NewVersion()["sdk"]; version != "" {
+		return version
+	}
+	return "unknown"
+}
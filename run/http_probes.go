@@ -0,0 +1,126 @@
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+// Paths served by the HTTPRunner.
+const (
+	solvePath   = "/"
+	healthPath  = "/healthz"
+	readyPath   = "/readyz"
+	infoPath    = "/info"
+	optionsPath = "/options"
+)
+
+// allowMethods reports whether the method of the request is one of the given
+// methods. If it is not, it responds with 405 Method Not Allowed.
+func (h *httpRunner[Input, Option, Solution]) allowMethods(
+	w http.ResponseWriter, req *http.Request, methods ...string,
+) bool {
+	for _, method := range methods {
+		if req.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	h.reject(w, NewError(ErrorCodeMethodNotAllowed,
+		fmt.Errorf("method %s not allowed", req.Method),
+	))
+	return false
+}
+
+// serveHealth reports that the server is alive.
+func (h *httpRunner[Input, Option, Solution]) serveHealth(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// serveReady reports whether the server accepts new runs. It is not ready
+// while it is shutting down or while all MaxParallel slots are taken.
+func (h *httpRunner[Input, Option, Solution]) serveReady(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	switch {
+	case h.draining.Load():
+		h.reject(w, NewError(ErrorCodeUnavailable, errors.New("not ready: shutting down")))
+	case h.ActiveRuns() >= cap(h.maxParallel):
+		h.reject(w, NewError(ErrorCodeUnavailable,
+			errors.New("not ready: max number of parallel requests reached"),
+		))
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// info is the response of the info endpoint.
+type info struct {
+	Version    schema.Version   `json:"version"`
+	Config     HTTPRunnerConfig `json:"config"`
+	ActiveRuns int              `json:"active_runs"`
+	QueuedRuns int              `json:"queued_runs"`
+}
+
+// serveInfo responds with the versions of the known dependencies and the
+// configuration of the runner.
+func (h *httpRunner[Input, Option, Solution]) serveInfo(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	// report the values in effect, which may have been overridden by
+	// HTTPRunnerOptions.
+	config := h.Runner.RunnerConfig()
+	config.Runner.HTTP.Address = h.httpServer.Addr
+	config.Runner.HTTP.MaxParallel = cap(h.maxParallel)
+	config.Runner.HTTP.QueueSize = cap(h.queue)
+	config.Runner.HTTP.QueueTimeout = h.queueTimeout
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(info{
+		Version:    schema.NewVersion(),
+		Config:     config,
+		ActiveRuns: h.ActiveRuns(),
+		QueuedRuns: h.QueuedRuns(),
+	})
+	if err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// mediaTypeMarkdown is the media type of the description of the options as a
+// markdown table.
+const mediaTypeMarkdown = "text/markdown"
+
+// serveOptions responds with the description of the options, see
+// DescribeOptions. It is JSON, unless markdown is preferred by the Accept
+// header.
+func (h *httpRunner[Input, Option, Solution]) serveOptions(
+	w http.ResponseWriter, req *http.Request,
+) {
+	descriptions := DescribeOptions[Option](h.envPrefix)
+	for _, accepted := range parseAccept(req.Header.Get("Accept")) {
+		if accepts(accepted, defaultMediaType) {
+			break
+		}
+		if accepts(accepted, mediaTypeMarkdown) {
+			w.Header().Set("Content-Type", mediaTypeMarkdown+"; charset=utf-8")
+			_, err := w.Write([]byte(OptionsMarkdown(descriptions)))
+			if err != nil {
+				h.httpServer.ErrorLog.Println(err)
+			}
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(descriptions); err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
@@ -0,0 +1,86 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ProfileHeader is the header of an http request that asks for its run to be
+// profiled, if a profile directory is configured. Its value is a comma
+// separated list of the profiles to record: cpu, heap, allocs, block, mutex,
+// trace or all. The profiles are written to the profile directory and named
+// after the request id, e.g. <request id>.cpu.pprof or <request id>.trace.out.
+// The CPU profile, the trace and the block and mutex profiles cover the whole
+// process, so only one run is profiled at a time. Other requests for profiles
+// are rejected with status 409 in the meantime. The allocs, block and mutex
+// profiles are cumulative over the runs profiled by the process, see
+// go tool pprof -base to compare one to the profile of an earlier run.
+const ProfileHeader = "X-Nextmv-Profile"
+
+// startRequestProfiles starts recording the profiles a request asks for with
+// the ProfileHeader. The returned stop function writes them once the run is
+// done. Without a configured profile directory the header is ignored. Unknown
+// profiles and requests that conflict with the profiles of another run are
+// rejected with ErrorCodeProfile and ErrorCodeProfileConflict.
+func (h *httpRunner[Input, Option, Solution]) startRequestProfiles(
+	ctx context.Context, req *http.Request, requestID string,
+) (_ context.Context, stop func(), err error) {
+	stop = func() {}
+	dir := h.Runner.RunnerConfig().Runner.HTTP.ProfileDir
+	header := req.Header.Get(ProfileHeader)
+	if dir == "" || header == "" {
+		return ctx, stop, nil
+	}
+	paths, err := requestProfilePaths(dir, requestID, header)
+	if err != nil {
+		return ctx, stop, NewError(ErrorCodeProfile, err)
+	}
+	profiles, err := startProfiles(paths)
+	if errors.Is(err, errProfiling) {
+		return ctx, stop, NewError(ErrorCodeProfileConflict, err)
+	}
+	if err != nil {
+		return ctx, stop, err
+	}
+	stop = func() {
+		if err := profiles.stop(); err != nil {
+			h.httpServer.ErrorLog.Printf("profiles of request %s: %v", requestID, err)
+		}
+	}
+	return withProfiles(ctx, profiles), stop, nil
+}
+
+// requestProfilePaths returns the paths of the profiles listed in the value
+// of a ProfileHeader, in dir and named after the request id.
+func requestProfilePaths(dir, requestID, header string) (profilePaths, error) {
+	var paths profilePaths
+	kinds := []struct {
+		name, ext string
+		path      *string
+	}{
+		{"cpu", ".pprof", &paths.cpu},
+		{"heap", ".pprof", &paths.heap},
+		{"allocs", ".pprof", &paths.allocs},
+		{"block", ".pprof", &paths.block},
+		{"mutex", ".pprof", &paths.mutex},
+		{"trace", ".out", &paths.trace},
+	}
+	for _, name := range strings.Split(header, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, kind := range kinds {
+			if name == "all" || name == kind.name {
+				*kind.path = filepath.Join(dir, requestID+"."+kind.name+kind.ext)
+				found = true
+			}
+		}
+		if !found {
+			return paths, fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	return paths, nil
+}
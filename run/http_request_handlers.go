@@ -3,9 +3,14 @@ package run
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 // SyncHTTPRequestHandler allows the input and option to be sent as body and
@@ -23,6 +28,10 @@ func SyncHTTPRequestHandler(
 		}, nil
 }
 
+// CallbackSignatureHeader is the header of a callback request that holds the
+// HMAC-SHA256 signature of the body, if a callback secret is configured.
+const CallbackSignatureHeader = "signature"
+
 // AsyncHTTPRequestHandlerOption configures an AsyncHTTPRequestHandler.
 type AsyncHTTPRequestHandlerOption func(*asyncHTTPHandler)
 
@@ -38,6 +47,65 @@ func RequestOverride(allow bool) AsyncHTTPRequestHandlerOption {
 	return func(h *asyncHTTPHandler) { h.requestOverride = allow }
 }
 
+// RequireCallback sets whether a callback url is required. If it is not
+// required and no callback url is configured or given in the request, the
+// result is only available by polling GET /runs/{id}/result.
+func RequireCallback(require bool) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) { h.requireCallback = require }
+}
+
+// HTTPClient sets the http client that is used to send the callback. By
+// default, http.DefaultClient is used.
+func HTTPClient(client *http.Client) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) { h.httpClient = client }
+}
+
+// CallbackRetries sets how often a failed callback is retried. Callbacks are
+// retried on network errors and on responses with status 429 or 5xx. The
+// default is 3.
+func CallbackRetries(retries int) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) { h.retries = retries }
+}
+
+// CallbackBackoff sets the wait time before the first retry of a callback. The
+// wait time is doubled for every further retry, up to maximum. The defaults
+// are 1s and 30s.
+func CallbackBackoff(initial, maximum time.Duration) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) {
+		h.initialBackoff = initial
+		h.maxBackoff = maximum
+	}
+}
+
+// CallbackTimeout sets the timeout of a single callback attempt. The default
+// is 30s, 0 means no timeout.
+func CallbackTimeout(timeout time.Duration) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) { h.timeout = timeout }
+}
+
+// CallbackSecret sets a secret that is shared with the receiver of the
+// callback. If set, the body of the callback is signed with HMAC-SHA256 and
+// the signature is sent in the CallbackSignatureHeader, so that the receiver
+// can authenticate the result with VerifyCallbackSignature.
+func CallbackSecret(secret []byte) AsyncHTTPRequestHandlerOption {
+	return func(h *asyncHTTPHandler) { h.secret = secret }
+}
+
+// SignCallback returns the signature of the body of a callback, as sent in the
+// CallbackSignatureHeader. The signature has the form sha256=<hex digest>.
+func SignCallback(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	// writing to a hash never fails
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallbackSignature reports whether signature is a valid signature of
+// the body of a callback for the given secret.
+func VerifyCallbackSignature(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(SignCallback(secret, body)))
+}
+
 // AsyncHTTPRequestHandler creates a new asynchronous HTTPRequestHandler. The
 // given options are used to configure the handler.
 func AsyncHTTPRequestHandler(
@@ -46,6 +114,11 @@ func AsyncHTTPRequestHandler(
 	handler := &asyncHTTPHandler{
 		httpClient:      http.DefaultClient,
 		requestOverride: true,
+		requireCallback: true,
+		retries:         3,
+		initialBackoff:  time.Second,
+		maxBackoff:      30 * time.Second,
+		timeout:         30 * time.Second,
 	}
 	for _, option := range options {
 		option(handler)
@@ -53,10 +126,33 @@ func AsyncHTTPRequestHandler(
 	return handler.Handler
 }
 
+type serverContextKey struct{}
+
+// withServerContext returns a context that carries the context of the server
+// lifetime, which outlives the request.
+func withServerContext(ctx, server context.Context) context.Context {
+	return context.WithValue(ctx, serverContextKey{}, server)
+}
+
+// serverContext returns the context of the server lifetime. It returns a
+// background context if there is none.
+func serverContext(ctx context.Context) context.Context {
+	if server, ok := ctx.Value(serverContextKey{}).(context.Context); ok {
+		return server
+	}
+	return context.Background()
+}
+
 type asyncHTTPHandler struct {
 	httpClient      *http.Client
 	callbackURL     string
 	requestOverride bool
+	requireCallback bool
+	retries         int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	timeout         time.Duration
+	secret          []byte
 }
 
 func (a asyncHTTPHandler) Handler(
@@ -68,35 +164,25 @@ func (a asyncHTTPHandler) Handler(
 		if headerCallbackURL != "" {
 			callbackURL = headerCallbackURL
 		}
-		if callbackURL == "" {
+		if callbackURL == "" && a.requireCallback {
 			return nil, nil, errors.New(
 				"callback_url not configured and not found in header",
 			)
 		}
-	} else if callbackURL == "" {
+	} else if callbackURL == "" && a.requireCallback {
 		return nil, nil, errors.New("callback_url not configured")
 	}
 
 	buf := new(bytes.Buffer)
-	callbackFunc := func(requestID, contentType string) (err error) {
-		// Create a new request
-		callbackReq, err := http.NewRequestWithContext(
-			context.Background(), "POST", callbackURL, buf,
-		)
-		if err != nil {
-			return err
-		}
-		// Set the GUID header
-		callbackReq.Header.Set("request_id", requestID)
-		// Set the encoding header
-		callbackReq.Header.Set("Content-Type", contentType)
-		// Send the request
-		resp, err := a.httpClient.Do(callbackReq)
-		if err != nil {
-			return err
+	// the callback is sent after the request is done, so it must not use the
+	// context of the request.
+	ctx := serverContext(req.Context())
+	callbackFunc := func(requestID, contentType string) error {
+		if callbackURL == "" {
+			// the result can only be polled.
+			return nil
 		}
-		err = resp.Body.Close()
-		return err
+		return a.callback(ctx, callbackURL, requestID, contentType, buf.Bytes())
 	}
 
 	body, err := io.ReadAll(req.Body)
@@ -114,3 +200,71 @@ func (a asyncHTTPHandler) Handler(
 		)
 	}, nil
 }
+
+// callback sends the body to the callback url and retries with exponential
+// backoff if the attempt failed with a transient error. The retries stop when
+// the context is canceled.
+func (a asyncHTTPHandler) callback(
+	ctx context.Context, callbackURL, requestID, contentType string, body []byte,
+) error {
+	backoff := a.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := a.sendCallback(ctx, callbackURL, requestID, contentType, body)
+		if err == nil || !retry || attempt >= a.retries {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, a.maxBackoff)
+	}
+}
+
+// sendCallback performs a single callback attempt. It reports whether a failed
+// attempt is worth retrying.
+func (a asyncHTTPHandler) sendCallback(
+	ctx context.Context, callbackURL, requestID, contentType string, body []byte,
+) (retry bool, err error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	// Create a new request
+	callbackReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, callbackURL, bytes.NewReader(body),
+	)
+	if err != nil {
+		return false, err
+	}
+	// Set the GUID header
+	callbackReq.Header.Set("request_id", requestID)
+	// Set the encoding header
+	callbackReq.Header.Set("Content-Type", contentType)
+	// Sign the body
+	if len(a.secret) > 0 {
+		callbackReq.Header.Set(CallbackSignatureHeader, SignCallback(a.secret, body))
+	}
+	// Send the request
+	resp, err := a.httpClient.Do(callbackReq)
+	if err != nil {
+		return true, err
+	}
+	// drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		return true, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError
+	return retry, fmt.Errorf(
+		"callback to %s failed with status %s", callbackURL, resp.Status,
+	)
+}
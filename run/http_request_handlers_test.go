@@ -0,0 +1,108 @@
+package run_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+// asyncCallback returns the callback of an asynchronous request whose output
+// is the given body.
+func asyncCallback(
+	t *testing.T, body string, options ...run.AsyncHTTPRequestHandlerOption,
+) run.Callback {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	handler := run.AsyncHTTPRequestHandler(options...)
+	callback, producer, err := handler(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := producer(req.Context(), run.HTTPRunnerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(data.Writer().(io.Writer), body); err != nil {
+		t.Fatal(err)
+	}
+	return callback
+}
+
+func TestAsyncCallbackRetries(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"solutions":[]}`
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(received, []byte(body)) {
+				t.Errorf("got %s; want %s", received, body)
+			}
+			signature := r.Header.Get(run.CallbackSignatureHeader)
+			if !run.VerifyCallbackSignature(secret, received, signature) {
+				t.Errorf("got invalid signature %q", signature)
+			}
+			if attempts.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		},
+	))
+	defer server.Close()
+
+	callback := asyncCallback(t, body,
+		run.CallbackURL(server.URL),
+		run.CallbackSecret(secret),
+		run.CallbackBackoff(time.Millisecond, time.Millisecond),
+	)
+	if err := callback("id", "application/json"); err != nil {
+		t.Errorf("got %v; want nil", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("got %d attempts; want 3", got)
+	}
+}
+
+func TestAsyncCallbackFailure(t *testing.T) {
+	tests := []struct {
+		status   int
+		attempts int32
+	}{
+		// client errors are not retried
+		{status: http.StatusBadRequest, attempts: 1},
+		// server errors are retried until the retries are exhausted
+		{status: http.StatusInternalServerError, attempts: 3},
+		{status: http.StatusTooManyRequests, attempts: 3},
+	}
+	for _, test := range tests {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(test.status)
+			},
+		))
+
+		callback := asyncCallback(t, "{}",
+			run.CallbackURL(server.URL),
+			run.CallbackRetries(2),
+			run.CallbackBackoff(time.Millisecond, time.Millisecond),
+		)
+		if err := callback("id", "application/json"); err == nil {
+			t.Errorf("got nil; want error for status %d", test.status)
+		}
+		if got := attempts.Load(); got != test.attempts {
+			t.Errorf("got %d attempts; want %d for status %d",
+				got, test.attempts, test.status)
+		}
+		server.Close()
+	}
+}
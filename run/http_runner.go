@@ -1,12 +1,19 @@
 package run
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nextmv-io/sdk/run/decode"
@@ -53,6 +60,27 @@ func SetMaxParallel[Input, Option, Solution any](maxParallel int) func(
 	}
 }
 
+// SetQueueSize sets the maximum number of requests that wait for a free slot
+// once MaxParallel requests are active. Requests beyond that are rejected with
+// 429 Too Many Requests.
+func SetQueueSize[Input, Option, Solution any](queueSize int) func(
+	*httpRunner[Input, Option, Solution],
+) {
+	return func(r *httpRunner[Input, Option, Solution]) {
+		r.setQueueSize(queueSize)
+	}
+}
+
+// SetQueueTimeout sets the maximum duration a request waits for a free slot
+// before it is rejected with 429 Too Many Requests. Zero means no limit.
+func SetQueueTimeout[Input, Option, Solution any](timeout time.Duration) func(
+	*httpRunner[Input, Option, Solution],
+) {
+	return func(r *httpRunner[Input, Option, Solution]) {
+		r.setQueueTimeout(timeout)
+	}
+}
+
 // SetHTTPRequestHandler sets the function that handles the http request.
 func SetHTTPRequestHandler[Input, Option, Solution any](
 	f HTTPRequestHandler) func(*httpRunner[Input, Option, Solution],
@@ -73,6 +101,17 @@ func SetRunnerOption[Input, Option, Solution any](
 	}
 }
 
+// SetJobStore sets the store that keeps track of asynchronous runs and their
+// results. By default, an in-memory store is used, which evicts finished runs
+// after the -runner.http.jobttl duration, see NewInMemoryJobStore.
+func SetJobStore[Input, Option, Solution any](s JobStore) func(
+	*httpRunner[Input, Option, Solution],
+) {
+	return func(r *httpRunner[Input, Option, Solution]) {
+		r.setJobStore(s)
+	}
+}
+
 // SetHTTPServer sets the http server. Note that if you want to set the address
 // or the logger of the http server you are setting through this option and you
 // want to make use of SetAddr and SetLogger, you should pass them after passing
@@ -91,27 +130,52 @@ type HTTPRunner[RunnerConfig, Input, Option, Solution any] interface {
 	Runner[RunnerConfig, Input, Option, Solution]
 	// ActiveRuns returns the number of currently active runs.
 	ActiveRuns() int
+	// QueuedRuns returns the number of requests waiting for a free slot.
+	QueuedRuns() int
 }
 
-// NewHTTPRunner creates a new NewHTTPRunner.
+// NewHTTPRunner creates a new NewHTTPRunner. It is configured by the command
+// line of the process and exits the program if it cannot be parsed, see
+// NewHTTPRunnerWith.
 func NewHTTPRunner[Input, Option, Solution any](
 	algorithm Algorithm[Input, Option, Solution],
 	options ...HTTPRunnerOption[Input, Option, Solution],
 ) HTTPRunner[HTTPRunnerConfig, Input, Option, Solution] {
+	runner, err := NewHTTPRunnerWith(OSCommandLine(), algorithm, options...)
+	exitOnError(err)
+	return runner
+}
+
+// NewHTTPRunnerWith is NewHTTPRunner for the given command line. Instead of
+// exiting the program, it returns an error if the command line cannot be
+// parsed.
+func NewHTTPRunnerWith[Input, Option, Solution any](
+	commandLine CommandLine,
+	algorithm Algorithm[Input, Option, Solution],
+	options ...HTTPRunnerOption[Input, Option, Solution],
+) (HTTPRunner[HTTPRunnerConfig, Input, Option, Solution], error) {
+	// the IOProducer will be dynamically set by the http request handler.
+	genericRunner, err := NewGenericRunner[HTTPRunnerConfig](
+		commandLine,
+		nil,
+		GenericDecoder[Input](decode.JSON()),
+		validate.JSON[Input](nil),
+		QueryParamDecoder[Option],
+		algorithm,
+		GenericEncoder[Solution, Option](encode.JSON()),
+	)
+	if err != nil {
+		return nil, err
+	}
 	runner := &httpRunner[Input, Option, Solution]{
-		// the IOProducer will be dynamically set by the http request handler.
-		Runner: GenericRunner[HTTPRunnerConfig](
-			nil,
-			GenericDecoder[Input](decode.JSON()),
-			validate.JSON[Input](nil),
-			QueryParamDecoder[Option],
-			algorithm,
-			GenericEncoder[Solution, Option](encode.JSON()),
-		),
+		Runner:    genericRunner,
+		envPrefix: commandLine.EnvPrefix,
 	}
 
 	runnerConfig := runner.Runner.RunnerConfig()
 	runner.maxParallel = make(chan struct{}, runnerConfig.Runner.HTTP.MaxParallel)
+	runner.queue = make(chan struct{}, runnerConfig.Runner.HTTP.QueueSize)
+	runner.queueTimeout = runnerConfig.Runner.HTTP.QueueTimeout
 
 	// default http server
 	runner.httpServer = &http.Server{
@@ -124,18 +188,48 @@ func NewHTTPRunner[Input, Option, Solution any](
 	// default handler to IOProducer
 	runner.httpRequestHandler = SyncHTTPRequestHandler
 
+	// default store for asynchronous runs
+	runner.jobStore = NewInMemoryJobStore(runnerConfig.Runner.HTTP.JobTTL)
+
+	runner.metrics = newHTTPMetrics()
+
+	runner.lifetime, runner.stopLifetime = context.WithCancel(context.Background())
+
+	runner.decoders = map[string]Decoder[Input]{}
+	runner.encoders = map[string]Encoder[Solution, Option]{}
+
 	for _, option := range options {
 		option(runner)
 	}
 
-	return runner
+	return runner, nil
 }
 
 type httpRunner[Input, Option, Solution any] struct {
 	Runner[HTTPRunnerConfig, Input, Option, Solution]
 	httpServer         *http.Server
 	maxParallel        chan struct{}
+	queue              chan struct{}
+	queueTimeout       time.Duration
 	httpRequestHandler HTTPRequestHandler
+	jobStore           JobStore
+	metrics            *httpMetrics
+	// decoders and encoders are registered by media type for content
+	// negotiation. encoderTypes keeps the order of registration.
+	decoders     map[string]Decoder[Input]
+	encoders     map[string]Encoder[Solution, Option]
+	encoderTypes []string
+	// activeRuns tracks runs, including asynchronous ones that outlive their
+	// request, so that they can be drained on shutdown.
+	activeRuns sync.WaitGroup
+	// draining is set once the runner is shutting down.
+	draining atomic.Bool
+	// lifetime is canceled when the shutdown is over, so that work that
+	// outlives its request, such as callback retries, stops in time.
+	lifetime     context.Context
+	stopLifetime context.CancelFunc
+	// envPrefix is the prefix of the environment variables of the runner.
+	envPrefix string
 }
 
 func (h *httpRunner[Input, Option, Solution]) setHTTPAddr(addr string) {
@@ -150,16 +244,34 @@ func (h *httpRunner[Input, Option, Solution]) setMaxParallel(maxParallel int) {
 	h.maxParallel = make(chan struct{}, maxParallel)
 }
 
+func (h *httpRunner[Input, Option, Solution]) setQueueSize(queueSize int) {
+	h.queue = make(chan struct{}, queueSize)
+}
+
+func (h *httpRunner[Input, Option, Solution]) setQueueTimeout(
+	timeout time.Duration,
+) {
+	h.queueTimeout = timeout
+}
+
 func (h *httpRunner[Input, Option, Solution]) ActiveRuns() int {
 	return len(h.maxParallel)
 }
 
+func (h *httpRunner[Input, Option, Solution]) QueuedRuns() int {
+	return len(h.queue)
+}
+
 func (h *httpRunner[Input, Option, Solution]) setHTTPRequestHandler(
 	f HTTPRequestHandler,
 ) {
 	h.httpRequestHandler = f
 }
 
+func (h *httpRunner[Input, Option, Solution]) setJobStore(s JobStore) {
+	h.jobStore = s
+}
+
 func (h *httpRunner[Input, Option, Solution]) setHTTPServer(s *http.Server) {
 	h.httpServer = s
 }
@@ -170,9 +282,33 @@ func (h *httpRunner[Input, Option, Solution]) setRunnerOption(
 	option(h.Runner)
 }
 
+// Run starts the http server. It blocks until the given context is canceled
+// or the process receives SIGINT or SIGTERM. Then the server stops accepting
+// new requests and waits for active runs, including pending callbacks of
+// asynchronous runs, to finish before returning.
 func (h *httpRunner[Input, Option, Solution]) Run(
-	_ context.Context,
+	ctx context.Context,
 ) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- h.listenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		// the server stopped without being asked to, e.g. because the address
+		// is already in use.
+		return err
+	case <-ctx.Done():
+	}
+
+	return h.shutdown()
+}
+
+func (h *httpRunner[Input, Option, Solution]) listenAndServe() error {
 	httpRunnerConfig := h.Runner.RunnerConfig()
 	if httpRunnerConfig.Runner.HTTP.Certificate != "" ||
 		httpRunnerConfig.Runner.HTTP.Key != "" {
@@ -184,26 +320,123 @@ func (h *httpRunner[Input, Option, Solution]) Run(
 	return h.httpServer.ListenAndServe()
 }
 
-// ServeHTTP implements the http.Handler interface.
+// shutdown gracefully stops the http server and drains the active runs within
+// the configured shutdown timeout. Pending callback retries stop once the
+// shutdown is over.
+func (h *httpRunner[Input, Option, Solution]) shutdown() error {
+	h.draining.Store(true)
+	defer h.stopLifetime()
+	ctx := context.Background()
+	timeout := h.Runner.RunnerConfig().Runner.HTTP.ShutdownTimeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// stop accepting requests and wait for the handlers to return, which
+	// covers all synchronous runs.
+	if err := h.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// wait for asynchronous runs and their callbacks.
+	drained := make(chan struct{})
+	go func() {
+		h.activeRuns.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf(
+			"shutdown timed out with %d active runs", h.ActiveRuns(),
+		)
+	}
+}
+
+// ServeHTTP implements the http.Handler interface. Runs are started by POST
+// requests to the root path. The other endpoints are GET /healthz, GET /readyz,
+// GET /info, GET /options, GET /metrics, GET /openapi.json and
+// GET /runs/{id}[/result].
 func (h *httpRunner[Input, Option, Solution]) ServeHTTP(
 	w http.ResponseWriter, req *http.Request,
 ) {
-	select {
-	case h.maxParallel <- struct{}{}:
+	path := req.URL.Path
+	recorder := &statusRecorder{ResponseWriter: w}
+	w = recorder
+	defer func() {
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		h.metrics.observeRequest(endpoint(path), recorder.status)
+	}()
+
+	switch {
+	case path == solvePath:
+		if h.allowMethods(w, req, http.MethodPost) {
+			h.serveRun(w, req)
+		}
+	case path == healthPath:
+		if h.allowMethods(w, req, http.MethodGet, http.MethodHead) {
+			h.serveHealth(w, req)
+		}
+	case path == readyPath:
+		if h.allowMethods(w, req, http.MethodGet, http.MethodHead) {
+			h.serveReady(w, req)
+		}
+	case path == infoPath:
+		if h.allowMethods(w, req, http.MethodGet) {
+			h.serveInfo(w, req)
+		}
+	case path == optionsPath:
+		if h.allowMethods(w, req, http.MethodGet) {
+			h.serveOptions(w, req)
+		}
+	case path == metricsPath:
+		if h.allowMethods(w, req, http.MethodGet) {
+			h.serveMetrics(w, req)
+		}
+	case path == openAPIPath:
+		if h.allowMethods(w, req, http.MethodGet) {
+			h.serveOpenAPI(w, req)
+		}
+	case strings.HasPrefix(path, runsPath):
+		if h.allowMethods(w, req, http.MethodGet) {
+			h.serveJob(w, req)
+		}
 	default:
-		// No free slot, so we immediately return an error.
-		http.Error(w, "max number of parallel requests exceeded",
-			http.StatusTooManyRequests)
+		h.reject(w, NewError(ErrorCodeNotFound, fmt.Errorf("path %s not found", path)))
+	}
+}
+
+// serveRun runs the algorithm for the request.
+func (h *httpRunner[Input, Option, Solution]) serveRun(
+	w http.ResponseWriter, req *http.Request,
+) {
+	if h.draining.Load() {
+		h.reject(w, NewError(ErrorCodeUnavailable, errors.New("server is shutting down")))
+		return
+	}
+
+	if err := h.acquireSlot(req.Context()); err != nil {
+		h.reject(w, NewError(ErrorCodeTooManyRequests, err))
 		return
 	}
 
+	h.activeRuns.Add(1)
+
 	// control mechanism to let the request by run async or not.
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
+		defer h.activeRuns.Done()
 		defer func() { <-h.maxParallel }()
 		// configure how to turn the request and response into an IOProducer.
-		callbackFunc, producer, err := h.httpRequestHandler(w, req)
+		callbackFunc, producer, err := h.httpRequestHandler(
+			w, req.WithContext(withServerContext(req.Context(), h.lifetime)),
+		)
 		async := callbackFunc != nil
 		if err != nil {
 			handleError(h.httpServer.ErrorLog, async, err, w)
@@ -221,28 +454,66 @@ func (h *httpRunner[Input, Option, Solution]) ServeHTTP(
 			wg.Done()
 			return
 		}
+		c, contentType, err := h.negotiate(req, contentTyper.ContentType())
+		if err != nil {
+			h.reject(w, err)
+			wg.Done()
+			return
+		}
 
+		// synchronous runs are stopped when the client goes away. Asynchronous
+		// runs outlive the request, so they must not inherit its cancellation.
+		ctx := WithPhaseObserver(req.Context(), h.metrics.observePhase)
+		ctx = withCodecs(ctx, c)
+		// every request has its own run data, which carries its id.
+		ctx = withData(ctx)
+		Set(ctx, RequestID, requestID)
+		ctx, stopProfiles, err := h.startRequestProfiles(ctx, req, requestID)
+		if err != nil {
+			h.reject(w, err)
+			wg.Done()
+			return
+		}
+		var job Job
+		var result bytes.Buffer
 		if async {
+			// register the job before the request id is handed out, so that
+			// the client can poll for it right away.
+			job = Job{
+				ID:          requestID,
+				Status:      JobQueued,
+				CreatedAt:   time.Now(),
+				ContentType: contentType,
+			}
+			h.saveJob(req.Context(), job)
+			// keep a copy of the output for clients that poll for it.
+			producer = teeIOProducer(producer, &result)
+
 			// write the guid to the response.
+			w.Header().Set("Location", runsPath+requestID)
 			_, err = w.Write([]byte(requestID))
 			if err != nil {
+				stopProfiles()
 				handleError(h.httpServer.ErrorLog, async, err, w)
 				wg.Done()
 				return
 			}
 			wg.Done()
 		} else {
-			w.Header().Add("Content-Type", contentTyper.ContentType())
+			ctx, producer = h.prepareStream(ctx, w, req, producer, contentType)
+			producer = h.prepareEncoding(w, req, producer)
 			defer wg.Done()
 		}
-		if err != nil {
-			handleError(h.httpServer.ErrorLog, async, err, w)
-			return
+		if async {
+			ctx = context.WithoutCancel(ctx)
+			job = h.startJob(ctx, job)
+		}
+		// run with the IOProducer of this request.
+		err = runWith(ctx, h.Runner, producer)
+		stopProfiles()
+		if async {
+			job = h.finishJob(ctx, job, result.Bytes(), err)
 		}
-		// get a copy of the genericRunner set the IOProducer and run it.
-		genericRunner := h.Runner
-		genericRunner.SetIOProducer(producer)
-		err = genericRunner.Run(context.Background())
 		if err != nil {
 			handleError(h.httpServer.ErrorLog, async, err, w)
 			return
@@ -250,8 +521,11 @@ func (h *httpRunner[Input, Option, Solution]) ServeHTTP(
 
 		// if the request is async, call the callbackFunc.
 		if async {
-			err = callbackFunc(requestID, contentTyper.ContentType())
+			err = callbackFunc(requestID, contentType)
+			h.metrics.observeCallback(err)
 			if err != nil {
+				job.CallbackError = err.Error()
+				h.saveJob(ctx, job)
 				handleError(h.httpServer.ErrorLog, async, err, w)
 				return
 			}
@@ -260,11 +534,64 @@ func (h *httpRunner[Input, Option, Solution]) ServeHTTP(
 	wg.Wait()
 }
 
+// acquireSlot takes one of the MaxParallel slots. If none is free, the request
+// waits in a bounded queue. Waiting requests are served in FIFO order. An
+// error is returned if the queue is full, the queue timeout expires or the
+// client goes away.
+func (h *httpRunner[Input, Option, Solution]) acquireSlot(
+	ctx context.Context,
+) error {
+	select {
+	case h.maxParallel <- struct{}{}:
+		return nil
+	default:
+	}
+
+	// No free slot, so we try to queue the request.
+	select {
+	case h.queue <- struct{}{}:
+	default:
+		return errors.New("max number of parallel requests exceeded")
+	}
+	defer func() { <-h.queue }()
+
+	var timeout <-chan time.Time
+	if h.queueTimeout > 0 {
+		timer := time.NewTimer(h.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case h.maxParallel <- struct{}{}:
+		return nil
+	case <-timeout:
+		return errors.New("timed out waiting for a free slot")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reject responds to a request that is rejected before its run starts. Unlike
+// handleError, it only logs errors that are not classified, since the others
+// are caused by the client.
+func (h *httpRunner[Input, Option, Solution]) reject(w http.ResponseWriter, err error) {
+	if classify(err).Code == "" {
+		h.httpServer.ErrorLog.Println(err)
+	}
+	if err := writeError(w, err); err != nil {
+		h.httpServer.ErrorLog.Println(err)
+	}
+}
+
+// handleError logs the error and, for synchronous requests, writes it as JSON
+// with the HTTP status of its classification.
 func handleError(log *log.Logger,
 	async bool, err error, w http.ResponseWriter,
 ) {
 	log.Println(err)
 	if !async {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := writeError(w, err); err != nil {
+			log.Println(err)
+		}
 	}
 }
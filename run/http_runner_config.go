@@ -8,18 +8,29 @@ import (
 // HTTPRunnerConfig defines the configuration of the HTTPRunner.
 type HTTPRunnerConfig struct {
 	Runner struct {
-		Log    *log.Logger
-		Output struct {
-			Solutions string `default:"last" usage:"Return all or last solution"`
-		}
+		Log      *log.Logger   `json:"-"`
+		Duration time.Duration `json:"duration" usage:"The maximum duration of a run, 0 means no limit"`
+		Output   struct {
+			Solutions string `json:"solutions" default:"last" usage:"Return all or last solution"`
+		} `json:"output"`
 		HTTP struct {
-			Address           string        `default:":9000" usage:"The host address"`
-			Certificate       string        `usage:"The certificate file path"`
-			Key               string        `usage:"The key file path"`
-			ReadHeaderTimeout time.Duration `default:"60s" usage:"The maximum duration for reading the request headers"`
-			MaxParallel       int           `default:"1" usage:"The max number of requests"`
-		}
-	}
+			Address           string        `json:"address" default:":9000" usage:"The host address"`
+			Certificate       string        `json:"certificate" usage:"The certificate file path"`
+			Key               string        `json:"key" usage:"The key file path"`
+			ReadHeaderTimeout time.Duration `json:"read_header_timeout" default:"60s" usage:"The maximum duration for reading the request headers"`
+			MaxParallel       int           `json:"max_parallel" default:"1" usage:"The max number of requests"`
+			QueueSize         int           `json:"queue_size" default:"0" usage:"The max number of requests waiting for a free slot"`
+			QueueTimeout      time.Duration `json:"queue_timeout" default:"0s" usage:"The maximum duration a request waits for a free slot, 0 means no limit"`
+			ShutdownTimeout   time.Duration `json:"shutdown_timeout" default:"30s" usage:"The maximum duration to wait for active runs on shutdown, 0 means no limit"`
+			ProfileDir        string        `json:"profile_dir" usage:"The directory to write the profiles of requests with the X-Nextmv-Profile header to, profiling is disabled if empty"`
+			JobTTL            time.Duration `json:"job_ttl" default:"1h" usage:"How long finished asynchronous runs and their results are kept in memory, 0 means forever"`
+		} `json:"http"`
+	} `json:"runner"`
+}
+
+// TimeLimit returns the maximum duration of a run.
+func (c HTTPRunnerConfig) TimeLimit() time.Duration {
+	return c.Runner.Duration
 }
 
 // Solutions returns the configured solutions.
@@ -0,0 +1,148 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Media types of streamed responses. A client asks for a stream with the
+// Accept header. Streams are only supported for JSON encoders.
+const (
+	// MediaTypeEventStream streams every solution as a Server-Sent Event.
+	MediaTypeEventStream = "text/event-stream"
+	// MediaTypeNDJSON streams every solution as a JSON line.
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// streamType returns the media type in which the output of a synchronous run
+// is streamed, or an empty string if it is not streamed. All solutions are
+// streamed as NDJSON if the runner is configured to return all solutions.
+func streamType(
+	req *http.Request, contentType string, solutions Solutions,
+) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "application/json" {
+		return ""
+	}
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if mediaType == MediaTypeEventStream || mediaType == MediaTypeNDJSON {
+			return mediaType
+		}
+	}
+	if solutions == All {
+		return MediaTypeNDJSON
+	}
+	return ""
+}
+
+// prepareStream sets the headers of a synchronous run. If the output is
+// streamed, all solutions are encoded and, for Server-Sent Events, the writer
+// of the producer is wrapped, so that every solution becomes an event.
+func (h *httpRunner[Input, Option, Solution]) prepareStream(
+	ctx context.Context,
+	w http.ResponseWriter,
+	req *http.Request,
+	producer IOProducer[HTTPRunnerConfig],
+	contentType string,
+) (context.Context, IOProducer[HTTPRunnerConfig]) {
+	solutions, _ := solutionsMode(ctx, h.Runner.RunnerConfig())
+	stream := streamType(req, contentType, solutions)
+	switch stream {
+	case "":
+		w.Header().Add("Content-Type", contentType)
+		return ctx, producer
+	case MediaTypeEventStream:
+		w.Header().Set("Cache-Control", "no-cache")
+		producer = eventStreamIOProducer(producer)
+	}
+	w.Header().Set("Content-Type", stream)
+	return withSolutions(ctx, All), producer
+}
+
+// eventStreamIOProducer wraps an IOProducer so that its output is written as
+// Server-Sent Events.
+func eventStreamIOProducer(
+	producer IOProducer[HTTPRunnerConfig],
+) IOProducer[HTTPRunnerConfig] {
+	return func(ctx context.Context, cfg HTTPRunnerConfig) (IOData, error) {
+		data, err := producer(ctx, cfg)
+		if err != nil {
+			return data, err
+		}
+		writer, ok := data.Writer().(io.Writer)
+		if !ok {
+			return data, nil
+		}
+		return eventStreamIOData{
+			IOData: data,
+			writer: &eventStreamWriter{writer: writer},
+		}, nil
+	}
+}
+
+type eventStreamIOData struct {
+	IOData
+	writer *eventStreamWriter
+}
+
+func (d eventStreamIOData) Writer() any {
+	return d.writer
+}
+
+// Close closes the wrapped IOData, if it can be closed.
+func (d eventStreamIOData) Close() error {
+	return closeIOData(d.IOData)
+}
+
+// eventStreamWriter collects everything written between two flushes and
+// writes it as a single solution event. Every line of it becomes a data line
+// of the event.
+type eventStreamWriter struct {
+	writer io.Writer
+	buf    bytes.Buffer
+	err    error
+}
+
+func (e *eventStreamWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return e.buf.Write(p)
+}
+
+// Flush writes the collected output as an event and flushes the underlying
+// writer.
+func (e *eventStreamWriter) Flush() error {
+	if e.err != nil || e.buf.Len() == 0 {
+		return e.err
+	}
+	e.err = writeEvent(e.writer, "solution", e.buf.Bytes())
+	e.buf.Reset()
+	if e.err != nil {
+		return e.err
+	}
+	return flush(e.writer)
+}
+
+// Close writes any output that was not flushed yet.
+func (e *eventStreamWriter) Close() error {
+	return e.Flush()
+}
+
+// writeEvent writes a Server-Sent Event with the given name and data.
+func writeEvent(w io.Writer, event string, data []byte) error {
+	var b bytes.Buffer
+	b.WriteString("event: " + event + "\n")
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,47 @@
+// Package columns maps the fields of a struct to the columns of a table, as
+// used by the CSV decoder and encoder.
+package columns
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Column is a column of a table that is backed by a struct field.
+type Column struct {
+	// Name is the name of the column in the header.
+	Name string
+	// Index is the index of the field in the struct.
+	Index int
+}
+
+// Of returns the columns of the struct type t in the order of its fields. The
+// name of a column is taken from the csv tag, then from the json tag and
+// otherwise it is the name of the field. Unexported fields, embedded fields
+// and fields tagged with csv:"-" are left out.
+func Of(t reflect.Type) []Column {
+	var columns []Column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := tagName(field.Tag.Get("csv"))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = tagName(field.Tag.Get("json"))
+		}
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		columns = append(columns, Column{Name: name, Index: i})
+	}
+	return columns
+}
+
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
@@ -1,9 +1,6 @@
 package run
 
 import (
-	"bufio"
-	"bytes"
-	"compress/gzip"
 	"io"
 )
 
@@ -37,34 +34,31 @@ func NewIOData(input any, option any, writer any) (data IOData, err error) {
 		}()
 	}
 
-	// Convert to buffered reader and read magic bytes
-	bufferedReader := bufio.NewReader(reader)
-	testBytes, err := bufferedReader.Peek(2)
-
-	// Test for gzip magic bytes and use corresponding reader, if given
-	if err == nil && testBytes[0] == 31 && testBytes[1] == 139 {
-		var gzipReader *gzip.Reader
-		if gzipReader, err = gzip.NewReader(bufferedReader); err != nil {
-			return ioData{}, err
-		}
-		reader = gzipReader
-	} else {
-		// Default case: assume text input
-		reader = bufferedReader
+	// Decompress the input, the compression is detected by its magic bytes.
+	decompressed, err := decompress(reader)
+	if err != nil {
+		return ioData{}, err
 	}
+	defer func() {
+		tempErr := decompressed.Close()
+		// the first error is the most important
+		if err == nil {
+			err = tempErr
+		}
+	}()
+	reader = decompressed
 
-	// copy input to buffer
-	buf := &bytes.Buffer{}
-	_, err = buf.ReadFrom(reader)
+	// keep the input, so that it can be read more than once. Large inputs are
+	// spooled to a temporary file instead of being held in memory.
+	input, err = newSpool(reader, InputMemoryLimit)
 	if err != nil {
 		return ioData{}, err
 	}
 
 	return ioData{
-		input:  input,
 		option: option,
 		writer: writer,
-		buf:    buf,
+		input:  input,
 	}, nil
 }
 
@@ -72,13 +66,13 @@ type ioData struct {
 	input  any
 	option any
 	writer any
-	buf    *bytes.Buffer
 }
 
+// Input returns the input. An input that was read by NewIOData is returned as
+// a new reader every time, which reads it from the start.
 func (d ioData) Input() (input any) {
-	// buffer was filled so use that instead of the original reader
-	if len(d.buf.Bytes()) > 0 {
-		return bytes.NewReader(d.buf.Bytes())
+	if s, ok := d.input.(*spool); ok {
+		return s.reader()
 	}
 	return d.input
 }
@@ -90,3 +84,28 @@ func (d ioData) Option() any {
 func (d ioData) Writer() any {
 	return d.writer
 }
+
+// Close releases the input, e.g. removes the temporary file it was spooled
+// to. The input cannot be read afterwards.
+func (d ioData) Close() error {
+	if s, ok := d.input.(*spool); ok {
+		return s.Close()
+	}
+	return nil
+}
+
+// closeIOData closes the IOData, if it can be closed.
+func closeIOData(data IOData) error {
+	if closer, ok := data.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// closeOption closes the source of the option, if it can be closed. Closing it
+// again is harmless, so the error is ignored.
+func closeOption(data IOData) {
+	if closer, ok := data.Option().(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
@@ -0,0 +1,167 @@
+package run_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/decode"
+	"github.com/nextmv-io/sdk/run/validate"
+)
+
+func TestNewIODataRereadsSpooledInput(t *testing.T) {
+	limit := run.InputMemoryLimit
+	run.InputMemoryLimit = 8
+	defer func() { run.InputMemoryLimit = limit }()
+
+	want := `{"message": "spooled to a temporary file"}`
+	data, err := run.NewIOData(strings.NewReader(want), nil, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := io.ReadAll(data.Input().(io.Reader))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got %v; want %v", string(got), want)
+		}
+	}
+	if err := data.(io.Closer).Close(); err != nil {
+		t.Errorf("got %v; want %v", err, nil)
+	}
+}
+
+func TestNewIODataKeepsNonReaderInput(t *testing.T) {
+	input := map[string]string{"message": "Hello"}
+	data, err := run.NewIOData(input, nil, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := data.Input().(map[string]string); !ok || got["message"] != "Hello" {
+		t.Errorf("got %v; want %v", data.Input(), input)
+	}
+}
+
+// closeRecorder is an option source that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRunClosesOptionOfInvalidInput(t *testing.T) {
+	algorithm := func(_ context.Context, _ struct{}, _ struct{}, _ chan<- any) error {
+		return nil
+	}
+	runner, err := run.NewCLIRunnerWith(run.CommandLine{Output: io.Discard}, algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	option := &closeRecorder{Reader: strings.NewReader("{}")}
+	runner.SetIOProducer(
+		func(context.Context, run.CLIRunnerConfig) (run.IOData, error) {
+			return run.NewIOData(strings.NewReader("not json"), option, io.Discard)
+		},
+	)
+	if err := runner.Run(context.Background()); err == nil {
+		t.Errorf("got %v; want an error", err)
+	}
+	if !option.closed {
+		t.Errorf("got %v; want %v", option.closed, true)
+	}
+}
+
+type benchmarkInput struct {
+	Stops []struct {
+		ID       string  `json:"id"`
+		Quantity int     `json:"quantity"`
+		Lon      float64 `json:"lon"`
+		Lat      float64 `json:"lat"`
+	} `json:"stops"`
+}
+
+// benchmarkData returns a JSON input of about the given size in bytes.
+func benchmarkData(size int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"stops":[`)
+	for i := 0; b.Len() < size; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":"stop-%d","quantity":%d,"lon":7.%d,"lat":51.%d}`, i, i%10, i, i)
+	}
+	b.WriteString(`]}`)
+	return b.Bytes()
+}
+
+// BenchmarkNewIOData reads inputs of different sizes, below and above
+// run.InputMemoryLimit, twice, like the validator and the decoder do. The
+// peak-MB metric is the largest heap in use while the input is held by the
+// IOData.
+func BenchmarkNewIOData(b *testing.B) {
+	for _, size := range []int{1 << 20, 16 << 20, 64 << 20} {
+		input := benchmarkData(size)
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(input)))
+			var peak uint64
+			var stats runtime.MemStats
+			for i := 0; i < b.N; i++ {
+				data, err := run.NewIOData(bytes.NewReader(input), nil, io.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < 2; j++ {
+					if _, err := io.Copy(io.Discard, data.Input().(io.Reader)); err != nil {
+						b.Fatal(err)
+					}
+				}
+				runtime.ReadMemStats(&stats)
+				if stats.HeapInuse > peak {
+					peak = stats.HeapInuse
+				}
+				if err := data.(io.Closer).Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+		})
+	}
+}
+
+// BenchmarkValidateAndDecode validates and decodes an input read by
+// NewIOData. The validator decodes the input while reading it, so that it is
+// not copied once more.
+func BenchmarkValidateAndDecode(b *testing.B) {
+	input := benchmarkData(1 << 20)
+	validator := validate.JSON[benchmarkInput](nil)
+	decoder := run.GenericDecoder[benchmarkInput](decode.JSON())
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		data, err := run.NewIOData(bytes.NewReader(input), nil, io.Discard)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := validator(context.Background(), data.Input()); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := decoder(context.Background(), data.Input()); err != nil {
+			b.Fatal(err)
+		}
+		if err := data.(io.Closer).Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus is the status of an asynchronous run.
+type JobStatus string
+
+// Statuses an asynchronous run goes through.
+const (
+	// JobQueued means the run was accepted but has not started yet.
+	JobQueued JobStatus = "queued"
+	// JobRunning means the run is in progress.
+	JobRunning JobStatus = "running"
+	// JobSucceeded means the run finished and its result is available.
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the run finished with an error.
+	JobFailed JobStatus = "failed"
+)
+
+// Job describes an asynchronous run. It is identified by the request id that
+// is returned to the client when the run is submitted.
+type Job struct {
+	ID            string     `json:"id"`
+	Status        JobStatus  `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CallbackError string     `json:"callback_error,omitempty"`
+	ContentType   string     `json:"content_type,omitempty"`
+}
+
+// ErrJobNotFound is returned by a JobStore if there is no job or no result for
+// the given id.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStore keeps track of asynchronous runs and their results, so that
+// clients can poll for them.
+type JobStore interface {
+	// SaveJob creates or updates a job.
+	SaveJob(ctx context.Context, job Job) error
+	// Job returns the job with the given id.
+	Job(ctx context.Context, id string) (Job, error)
+	// SaveResult stores the encoded output of the job with the given id.
+	SaveResult(ctx context.Context, id string, result []byte) error
+	// Result returns the encoded output of the job with the given id.
+	Result(ctx context.Context, id string) ([]byte, error)
+}
+
+// NewInMemoryJobStore creates a JobStore that keeps jobs and results in
+// memory. Finished jobs and their results are evicted once they finished more
+// than ttl ago, so that the memory of a long-running server does not grow
+// with every run. A ttl of 0 keeps them for the lifetime of the process.
+func NewInMemoryJobStore(ttl time.Duration) JobStore {
+	return &inMemoryJobStore{
+		ttl:     ttl,
+		jobs:    map[string]Job{},
+		results: map[string][]byte{},
+	}
+}
+
+type inMemoryJobStore struct {
+	mutex   sync.RWMutex
+	ttl     time.Duration
+	jobs    map[string]Job
+	results map[string][]byte
+	// evicted is the time of the last eviction.
+	evicted time.Time
+}
+
+func (s *inMemoryJobStore) SaveJob(_ context.Context, job Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evict()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *inMemoryJobStore) Job(_ context.Context, id string) (Job, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok || s.expired(job) {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (s *inMemoryJobStore) SaveResult(
+	_ context.Context, id string, result []byte,
+) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results[id] = result
+	return nil
+}
+
+func (s *inMemoryJobStore) Result(_ context.Context, id string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result, ok := s.results[id]
+	if !ok || s.expired(s.jobs[id]) {
+		return nil, ErrJobNotFound
+	}
+	return result, nil
+}
+
+// expired reports whether the job finished more than the ttl ago.
+func (s *inMemoryJobStore) expired(job Job) bool {
+	return s.ttl > 0 && job.FinishedAt != nil && time.Since(*job.FinishedAt) > s.ttl
+}
+
+// evict removes the expired jobs and their results. Expired jobs are not
+// returned anyway, so they are only looked for once in a while, instead of
+// every time a job is saved.
+func (s *inMemoryJobStore) evict() {
+	if s.ttl <= 0 || time.Since(s.evicted) < s.ttl/10 {
+		return
+	}
+	s.evicted = time.Now()
+	for id, job := range s.jobs {
+		if s.expired(job) {
+			delete(s.jobs, id)
+			delete(s.results, id)
+		}
+	}
+}
+
+// NewFileJobStore creates a JobStore that writes jobs and results to the given
+// directory. The directory is created if it does not exist. Jobs are stored as
+// <id>.json and results as <id>.result, so they survive restarts and can be
+// shared between replicas via a common volume.
+func NewFileJobStore(dir string) (JobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return fileJobStore{dir: dir}, nil
+}
+
+type fileJobStore struct {
+	dir string
+}
+
+func (s fileJobStore) SaveJob(_ context.Context, job Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	path, err := s.path(job.ID, ".json")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+func (s fileJobStore) Job(_ context.Context, id string) (job Job, err error) {
+	b, err := s.read(id, ".json")
+	if err != nil {
+		return job, err
+	}
+	err = json.Unmarshal(b, &job)
+	return job, err
+}
+
+func (s fileJobStore) SaveResult(
+	_ context.Context, id string, result []byte,
+) error {
+	path, err := s.path(id, ".result")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, result)
+}
+
+func (s fileJobStore) Result(_ context.Context, id string) ([]byte, error) {
+	return s.read(id, ".result")
+}
+
+func (s fileJobStore) read(id, extension string) ([]byte, error) {
+	path, err := s.path(id, extension)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrJobNotFound
+	}
+	return b, err
+}
+
+// path returns the path of the file for the given id. The id is part of the
+// request path of the polling endpoints, so it must not be able to escape the
+// directory of the store.
+func (s fileJobStore) path(id, extension string) (string, error) {
+	if id == "" || id == "." || id == ".." ||
+		strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid job id %q", id)
+	}
+	return filepath.Join(s.dir, id+extension), nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path, so
+// that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
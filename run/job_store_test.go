@@ -0,0 +1,105 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func TestJobStores(t *testing.T) {
+	fileStore, err := run.NewFileJobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]run.JobStore{
+		"memory": run.NewInMemoryJobStore(0),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testJobStore(t, store)
+		})
+	}
+}
+
+func testJobStore(t *testing.T, store run.JobStore) {
+	ctx := context.Background()
+	id := "0d9dbe3c-3f3b-4cb8-a1b5-5a0ed4b5b1a4"
+
+	if _, err := store.Job(ctx, id); !errors.Is(err, run.ErrJobNotFound) {
+		t.Errorf("got %v; want %v", err, run.ErrJobNotFound)
+	}
+
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := run.Job{ID: id, Status: run.JobQueued, CreatedAt: created}
+	if err := store.SaveJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	job.Status = run.JobSucceeded
+	if err := store.SaveJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Job(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != run.JobSucceeded || !got.CreatedAt.Equal(created) {
+		t.Errorf("got %+v; want %+v", got, job)
+	}
+
+	if _, err := store.Result(ctx, id); !errors.Is(err, run.ErrJobNotFound) {
+		t.Errorf("got %v; want %v", err, run.ErrJobNotFound)
+	}
+	if err := store.SaveResult(ctx, id, []byte(`{"solutions":[]}`)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Result(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `{"solutions":[]}` {
+		t.Errorf("got %s; want %s", result, `{"solutions":[]}`)
+	}
+}
+
+func TestInMemoryJobStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	store := run.NewInMemoryJobStore(time.Minute)
+	finished := time.Now().Add(-time.Hour)
+	jobs := []run.Job{
+		{ID: "expired", Status: run.JobSucceeded, FinishedAt: &finished},
+		{ID: "running", Status: run.JobRunning},
+	}
+	for _, job := range jobs {
+		if err := store.SaveJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveResult(ctx, job.ID, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Job(ctx, "expired"); !errors.Is(err, run.ErrJobNotFound) {
+		t.Errorf("got %v; want %v", err, run.ErrJobNotFound)
+	}
+	if _, err := store.Result(ctx, "expired"); !errors.Is(err, run.ErrJobNotFound) {
+		t.Errorf("got %v; want %v", err, run.ErrJobNotFound)
+	}
+	if _, err := store.Result(ctx, "running"); err != nil {
+		t.Errorf("got %v; want %v", err, nil)
+	}
+}
+
+func TestFileJobStoreInvalidID(t *testing.T) {
+	store, err := run.NewFileJobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "..", "../secret", `a\b`} {
+		if _, err := store.Job(context.Background(), id); err == nil {
+			t.Errorf("got nil; want error for id %q", id)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package run
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nextmv-io/sdk/run/validate"
+)
+
+// optionConstraint holds the constraints of an option field, which are given
+// by its tags:
+//
+//	min:"1"         the minimum of a number or duration, or the minimum
+//	                length of a string, slice or map
+//	max:"10"        the maximum, like min
+//	enum:"a,b,c"    the allowed values, or the allowed elements of a slice
+//	required:"true" the value must not be zero
+//
+// The option of a run is checked against them once the values of all sources
+// are merged, before the algorithm runs. The min, max and enum constraints do
+// not apply to zero values, so that options without a default can be left
+// out unless they are required.
+type optionConstraint struct {
+	field    optionField
+	required bool
+	min, max *float64
+	enum     []any
+	// minTag, maxTag and enumTag are the values of the tags.
+	minTag, maxTag, enumTag string
+}
+
+// optionConstraints returns the constraints of the fields of the option type
+// t. Fields without constraints are left out. An error is returned if a tag
+// cannot be parsed or does not apply to the type of its field.
+func optionConstraints(t reflect.Type, fields []optionField) ([]optionConstraint, error) {
+	var constraints []optionConstraint
+	for _, field := range fields {
+		structField := structFieldByIndex(t, field.index)
+		fieldType := structField.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		constraint := optionConstraint{field: field}
+		constraint.minTag, constraint.maxTag = structField.Tag.Get("min"), structField.Tag.Get("max")
+		constraint.enumTag = structField.Tag.Get("enum")
+		required := structField.Tag.Get("required")
+		if constraint.minTag == "" && constraint.maxTag == "" &&
+			constraint.enumTag == "" && required == "" {
+			continue
+		}
+		var err error
+		if required != "" {
+			if constraint.required, err = strconv.ParseBool(required); err != nil {
+				return nil, fmt.Errorf("option %s: required: %w", field.flag, err)
+			}
+		}
+		if constraint.min, err = parseBound(fieldType, constraint.minTag); err != nil {
+			return nil, fmt.Errorf("option %s: min: %w", field.flag, err)
+		}
+		if constraint.max, err = parseBound(fieldType, constraint.maxTag); err != nil {
+			return nil, fmt.Errorf("option %s: max: %w", field.flag, err)
+		}
+		if constraint.enumTag != "" {
+			elemType := fieldType
+			if elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array {
+				elemType = elemType.Elem()
+			}
+			for _, s := range strings.Split(constraint.enumTag, ",") {
+				value, err := parseScalar(elemType, strings.TrimSpace(s))
+				if err != nil {
+					return nil, fmt.Errorf("option %s: enum: %w", field.flag, err)
+				}
+				constraint.enum = append(constraint.enum, value)
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints, nil
+}
+
+// byFlag returns the constraints by the flags of their fields.
+func byFlag(constraints []optionConstraint) map[string]optionConstraint {
+	m := make(map[string]optionConstraint, len(constraints))
+	for _, constraint := range constraints {
+		m[constraint.field.flag] = constraint
+	}
+	return m
+}
+
+// validateOption checks the option struct v against the constraints. The
+// violations are returned as a validate.Error, like those of an input.
+func validateOption(v reflect.Value, constraints []optionConstraint) error {
+	var details []validate.Detail
+	for _, constraint := range constraints {
+		message := constraint.check(v)
+		if message == "" {
+			continue
+		}
+		details = append(details, validate.Detail{
+			Location: "/" + strings.Join(constraint.field.keys, "/"),
+			Message:  constraint.field.flag + ": " + message,
+		})
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return &validate.Error{Details: details}
+}
+
+// check returns the violation of the constraint by the field of the option
+// struct v, or an empty string. Zero values are only violations of required
+// fields, the other constraints apply to values that are given.
+func (c optionConstraint) check(v reflect.Value) string {
+	value, ok := fieldValue(v, c.field.index)
+	if !ok || value.IsZero() {
+		if c.required {
+			return "is required"
+		}
+		return ""
+	}
+	size := measure(value)
+	switch {
+	case c.min != nil && size < *c.min:
+		return "must be at least " + c.minTag
+	case c.max != nil && size > *c.max:
+		return "must be at most " + c.maxTag
+	}
+	if len(c.enum) == 0 {
+		return ""
+	}
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		if !c.allows(value) {
+			return "must be one of " + c.enumTag
+		}
+		return ""
+	}
+	for i := 0; i < value.Len(); i++ {
+		if !c.allows(value.Index(i)) {
+			return fmt.Sprintf("element %d must be one of %s", i, c.enumTag)
+		}
+	}
+	return ""
+}
+
+// allows reports whether value is one of the enum values.
+func (c optionConstraint) allows(value reflect.Value) bool {
+	for _, allowed := range c.enum {
+		if canonical(value) == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns the value of the field with the given index path in the
+// struct v. It reports false if a nil pointer is on the way.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// parseBound parses the value of a min or max tag for a field of type t. It
+// returns nil for an empty tag.
+func parseBound(t reflect.Type, s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var bound float64
+	var err error
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		var length int
+		length, err = strconv.Atoi(s)
+		bound = float64(length)
+	default:
+		var value reflect.Value
+		value, err = parseValue(t, s)
+		if err == nil && !isNumber(t.Kind()) {
+			err = fmt.Errorf("not supported for %s", t)
+		}
+		if err == nil {
+			bound = measure(value)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bound, nil
+}
+
+// parseScalar parses s as a value of type t and returns it in the form
+// canonical returns.
+func parseScalar(t reflect.Type, s string) (any, error) {
+	value, err := parseValue(t, s)
+	if err != nil {
+		return nil, err
+	}
+	return canonical(value), nil
+}
+
+// parseValue parses s as a value of type t. Durations are given as strings,
+// such as "1m30s".
+func parseValue(t reflect.Type, s string) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+	kind := t.Kind()
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(int64(d))
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(i)
+	case kind >= reflect.Uint && kind <= reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(u)
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetFloat(f)
+	case kind == reflect.String:
+		value.SetString(s)
+	case kind == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(b)
+	default:
+		return value, fmt.Errorf("not supported for %s", t)
+	}
+	return value, nil
+}
+
+// measure returns the number, or the length, a min or max tag bounds.
+func measure(v reflect.Value) float64 {
+	kind := v.Kind()
+	switch {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return float64(v.Int())
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return float64(v.Uint())
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return v.Float()
+	case kind == reflect.String, kind == reflect.Slice,
+		kind == reflect.Map, kind == reflect.Array:
+		return float64(v.Len())
+	default:
+		return 0
+	}
+}
+
+// canonical returns the value in a comparable form, in which numbers of
+// different types are equal if their values are.
+func canonical(v reflect.Value) any {
+	switch kind := v.Kind(); {
+	case kind == reflect.String:
+		return v.String()
+	case kind == reflect.Bool:
+		return v.Bool()
+	case isNumber(kind):
+		return measure(v)
+	default:
+		return nil
+	}
+}
+
+// isNumber reports whether kind is the kind of a number.
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
@@ -0,0 +1,132 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/runtest"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+type constrainedOptions struct {
+	Duration time.Duration `json:"duration" default:"1s" min:"0s" max:"1m" usage:"Run | sleep."`
+	Strategy string        `json:"strategy" default:"fast" enum:"fast,thorough"`
+	Workers  int           `json:"workers" default:"1" min:"1"`
+	Tags     []string      `json:"tags" enum:"a,b"`
+	Name     string        `json:"name" required:"true"`
+	Mode     string        `json:"mode" enum:"a,b"`
+	Limit    int           `json:"limit" min:"1"`
+}
+
+func constrainedAlgorithm(
+	_ context.Context, _ any, opts constrainedOptions, solutions chan<- schema.Output,
+) error {
+	solutions <- schema.NewOutput[any](opts)
+	return nil
+}
+
+func TestOptionConstraints(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []run.ErrorDetail
+	}{
+		{[]string{"-name", "x"}, nil},
+		{[]string{"-name", "x", "-tags", "a,b", "-strategy", "thorough"}, nil},
+		{[]string{"-name", "x", "-mode", "b", "-limit", "2"}, nil},
+		{
+			[]string{"-name", "x", "-mode", "c", "-limit", "-1"},
+			[]run.ErrorDetail{
+				{Location: "/mode", Message: "mode: must be one of a,b"},
+				{Location: "/limit", Message: "limit: must be at least 1"},
+			},
+		},
+		{nil, []run.ErrorDetail{{Location: "/name", Message: "name: is required"}}},
+		{
+			[]string{"-name", "x", "-duration", "2m", "-workers", "-1"},
+			[]run.ErrorDetail{
+				{Location: "/duration", Message: "duration: must be at most 1m"},
+				{Location: "/workers", Message: "workers: must be at least 1"},
+			},
+		},
+		{
+			[]string{"-name", "x", "-strategy", "slow", "-tags", "a,c"},
+			[]run.ErrorDetail{
+				{Location: "/strategy", Message: "strategy: must be one of fast,thorough"},
+				{Location: "/tags", Message: "tags: element 1 must be one of a,b"},
+			},
+		},
+	}
+	for _, test := range tests {
+		_, err := runtest.CLI(context.Background(), constrainedAlgorithm, []byte("{}"), test.args)
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("got %v; want %v", err, nil)
+			}
+			continue
+		}
+		var runErr *run.Error
+		if !errors.As(err, &runErr) || runErr.Code != run.ErrorCodeOption {
+			t.Errorf("got %v; want %v", err, run.ErrorCodeOption)
+			continue
+		}
+		if !reflect.DeepEqual(runErr.Details, test.want) {
+			t.Errorf("got %v; want %v", runErr.Details, test.want)
+		}
+	}
+}
+
+func TestOptionConstraintsInvalidTag(t *testing.T) {
+	type options struct {
+		Enabled bool `min:"1"`
+	}
+	algorithm := func(_ context.Context, _ any, _ options, _ chan<- any) error {
+		return nil
+	}
+	_, err := run.NewCLIRunnerWith(run.CommandLine{}, algorithm)
+	if err == nil {
+		t.Errorf("got %v; want an error", err)
+	}
+}
+
+func TestDescribeOptions(t *testing.T) {
+	descriptions := run.DescribeOptions[constrainedOptions]("APP")
+	want := run.OptionDescription{
+		Flag:    "duration",
+		Env:     "APP_DURATION",
+		Query:   "Duration",
+		Type:    "duration",
+		Default: "1s",
+		Usage:   "Run | sleep.",
+		Min:     "0s",
+		Max:     "1m",
+	}
+	if len(descriptions) != 7 {
+		t.Fatalf("got %v; want %v", len(descriptions), 7)
+	}
+	if !reflect.DeepEqual(descriptions[0], want) {
+		t.Errorf("got %v; want %v", descriptions[0], want)
+	}
+	markdown := run.OptionsMarkdown(descriptions)
+	row := "| `duration` | `APP_DURATION` | `Duration` | duration | `1s` | min 0s, max 1m | Run \\| sleep. |"
+	if !strings.Contains(markdown, row) {
+		t.Errorf("got %v; want %v", markdown, row)
+	}
+}
+
+func TestDescribeOptionsRequired(t *testing.T) {
+	type options struct {
+		A string `json:"a" required:"1"`
+		B string `json:"b" required:"TRUE"`
+		C string `json:"c" required:"false"`
+	}
+	for i, want := range []bool{true, true, false} {
+		if got := run.DescribeOptions[options]("")[i].Required; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	}
+}
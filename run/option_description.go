@@ -0,0 +1,113 @@
+package run
+
+import (
+	"reflect"
+	"strings"
+)
+
+// OptionDescription describes a field of the options, see DescribeOptions.
+type OptionDescription struct {
+	// Flag is the name of the command line flag, e.g. "greeting.prefix".
+	Flag string `json:"flag"`
+	// Env is the name of the environment variable.
+	Env string `json:"env"`
+	// Query is the name of the query parameter of an http request.
+	Query string `json:"query"`
+	// Type is the Go type of the field, durations are "duration".
+	Type string `json:"type"`
+	// Default is the value of the default tag.
+	Default string `json:"default,omitempty"`
+	// Usage is the value of the usage tag.
+	Usage string `json:"usage,omitempty"`
+	// Required, Min, Max and Enum are the values of the constraint tags of the
+	// field, see the package documentation.
+	Required bool     `json:"required,omitempty"`
+	Min      string   `json:"min,omitempty"`
+	Max      string   `json:"max,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+}
+
+// DescribeOptions describes the fields of the Option type as FlagParser and
+// QueryParamDecoder read them, together with their defaults and constraints.
+// The names of the environment variables are prefixed with envPrefix, see
+// EnvPrefix. If a constraint tag is invalid, which runners reject, the
+// constraints are left out.
+func DescribeOptions[Option any](envPrefix string) []OptionDescription {
+	return describeOptions(reflect.TypeOf(new(Option)).Elem(), envPrefix)
+}
+
+func describeOptions(t reflect.Type, prefix string) []OptionDescription {
+	fields := optionFields(t)
+	constraints, _ := optionConstraints(t, fields)
+	constraintsByFlag := byFlag(constraints)
+	descriptions := make([]OptionDescription, 0, len(fields))
+	for _, field := range fields {
+		structField := structFieldByIndex(t, field.index)
+		fieldType := structField.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		typeName := fieldType.String()
+		if fieldType == durationType {
+			typeName = "duration"
+		}
+		constraint := constraintsByFlag[field.flag]
+		description := OptionDescription{
+			Flag:     field.flag,
+			Env:      envFieldName(envPrefix(prefix), field),
+			Query:    field.queryKey(t),
+			Type:     typeName,
+			Default:  structField.Tag.Get("default"),
+			Usage:    structField.Tag.Get("usage"),
+			Required: constraint.required,
+			Min:      constraint.minTag,
+			Max:      constraint.maxTag,
+		}
+		if constraint.enumTag != "" {
+			for _, value := range strings.Split(constraint.enumTag, ",") {
+				description.Enum = append(description.Enum, strings.TrimSpace(value))
+			}
+		}
+		descriptions = append(descriptions, description)
+	}
+	return descriptions
+}
+
+// OptionsMarkdown renders the descriptions of options as a markdown table.
+func OptionsMarkdown(descriptions []OptionDescription) string {
+	var sb strings.Builder
+	sb.WriteString("| Flag | Env | Query | Type | Default | Constraints | Usage |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, d := range descriptions {
+		var constraints []string
+		if d.Required {
+			constraints = append(constraints, "required")
+		}
+		if d.Min != "" {
+			constraints = append(constraints, "min "+d.Min)
+		}
+		if d.Max != "" {
+			constraints = append(constraints, "max "+d.Max)
+		}
+		if len(d.Enum) > 0 {
+			constraints = append(constraints, "one of "+strings.Join(d.Enum, ", "))
+		}
+		cells := []string{
+			code(d.Flag), code(d.Env), code(d.Query), d.Type, code(d.Default),
+			strings.Join(constraints, ", "), d.Usage,
+		}
+		for i, cell := range cells {
+			cells[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return sb.String()
+}
+
+// code formats s as inline code, unless it is empty.
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,253 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itzg/go-flagsfiller"
+)
+
+// optionField is a leaf field of an option struct, as it is exposed by
+// FlagParser.
+type optionField struct {
+	// index is the index path of the field, see reflect.Value.FieldByIndex.
+	index []int
+	// keys is the path of the field in a JSON or YAML document.
+	keys []string
+	// flag is the name of the flag of the field. It identifies the field.
+	flag string
+	// env is the name of the environment variable of the field, without the
+	// EnvPrefix, and envTag whether it is given by an env tag.
+	env    string
+	envTag bool
+}
+
+// optionFields returns the leaf fields of the option struct type t. Fields
+// are named the same way as FlagParser names them.
+func optionFields(t reflect.Type) []optionField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return walkOptionFields(t, nil, nil, "")
+}
+
+func walkOptionFields(
+	t reflect.Type, index []int, keys []string, prefix string,
+) []optionField {
+	if prefix != "" {
+		prefix += "-"
+	}
+	var fields []optionField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		flagName, hasFlagTag := field.Tag.Lookup("flag")
+		if hasFlagTag && flagName == "" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		fieldKeys := append(append([]string{}, keys...), jsonKey(field))
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != timeType:
+			fields = append(fields, walkOptionFields(
+				field.Type, fieldIndex, fieldKeys, prefix+field.Name,
+			)...)
+		case field.Type.Kind() == reflect.Pointer &&
+			field.Type.Elem().Kind() == reflect.Struct:
+			// pointers to structs are not prefixed by FlagParser.
+			fields = append(fields, walkOptionFields(
+				field.Type.Elem(), fieldIndex, fieldKeys, field.Name,
+			)...)
+		default:
+			name := prefix + field.Name
+			if !hasFlagTag {
+				flagName = strings.ToLower(strings.ReplaceAll(name, "-", "."))
+			}
+			env, envTag := field.Tag.Lookup("env")
+			if !envTag {
+				env = flagsfiller.ScreamingSnakeRenamer()(name)
+			}
+			fields = append(fields, optionField{
+				index:  fieldIndex,
+				keys:   fieldKeys,
+				flag:   flagName,
+				env:    env,
+				envTag: envTag,
+			})
+		}
+	}
+	return fields
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonKey returns the key of the field in a JSON document.
+func jsonKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// field returns the value of the field in the addressable option struct v.
+// Pointers to structs on the way are replaced by copies, so that setting the
+// field never changes an option struct v shares pointers with.
+func (f optionField) field(v reflect.Value) reflect.Value {
+	for _, i := range f.index {
+		if v.Kind() == reflect.Pointer {
+			clone := reflect.New(v.Type().Elem())
+			if !v.IsNil() {
+				clone.Elem().Set(v.Elem())
+			}
+			v.Set(clone)
+			v = clone.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// queryKey returns the name of the field as a query parameter, as it is
+// decoded by QueryParamDecoder, e.g. "Greeting.Prefix". t is the option type.
+func (f optionField) queryKey(t reflect.Type) string {
+	names := make([]string, len(f.index))
+	for i, index := range f.index {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		field := t.Field(index)
+		names[i], _, _ = strings.Cut(field.Tag.Get("schema"), ",")
+		if names[i] == "" {
+			names[i] = field.Name
+		}
+		t = field.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// lookup returns the value of the field in a decoded JSON or YAML document.
+// Keys are matched case-insensitively, like encoding/json does.
+func (f optionField) lookup(document map[string]any) (any, bool) {
+	var value any = document
+	for _, key := range f.keys {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; ok {
+			continue
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, key) {
+				value, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// set sets the field in the option struct v to a value of a decoded JSON or
+// YAML document. Durations may be given as strings, such as "1m30s".
+func (f optionField) set(v reflect.Value, value any) error {
+	return f.setValue(f.field(v), value)
+}
+
+// setValue sets the value of the field, see set.
+func (f optionField) setValue(field reflect.Value, value any) error {
+	if s, ok := value.(string); ok && field.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("option %s: %w", strings.Join(f.keys, "."), err)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("option %s: %w", strings.Join(f.keys, "."), err)
+	}
+	target := reflect.New(field.Type())
+	if err := json.Unmarshal(b, target.Interface()); err != nil {
+		return fmt.Errorf("option %s: %w", strings.Join(f.keys, "."), err)
+	}
+	field.Set(target.Elem())
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// OptionSource is the source of the value of an option field. Sources are
+// listed from the lowest to the highest precedence: a field set by a source
+// keeps the value of that source, unless a source of higher precedence sets it
+// as well.
+type OptionSource string
+
+// Sources of option values.
+const (
+	// OptionSourceDefault is the default value of the field, as given by its
+	// default tag.
+	OptionSourceDefault OptionSource = "default"
+	// OptionSourceConfig is a config file, see FlagParser.
+	OptionSourceConfig OptionSource = "config"
+	// OptionSourceEnv is an environment variable.
+	OptionSourceEnv OptionSource = "env"
+	// OptionSourceFlag is a command line flag.
+	OptionSourceFlag OptionSource = "flag"
+	// OptionSourceFile is an options file, see FileOptionDecoder.
+	OptionSourceFile OptionSource = "file"
+	// OptionSourceRequest is an http request, see QueryParamDecoder.
+	OptionSourceRequest OptionSource = "request"
+	// OptionSourceDecoder is an option decoder that does not report the fields
+	// it sets. The fields it sets to a non-zero value are taken from it.
+	OptionSourceDecoder OptionSource = "decoder"
+)
+
+type optionSourcesKey struct{}
+
+// OptionSources returns the source of every field of the effective option of
+// a run, keyed by the flag name of the field, e.g. "greeting.prefix". It can
+// be used by an algorithm to echo where its options came from.
+func OptionSources(ctx context.Context) map[string]OptionSource {
+	sources, _ := ctx.Value(optionSourcesKey{}).(map[string]OptionSource)
+	return sources
+}
+
+// optionPresence records the option fields an option decoder has set and
+// their source, so that they can be merged with the options of other sources
+// field by field.
+type optionPresence struct {
+	mutex  sync.Mutex
+	fields map[string]OptionSource
+}
+
+type optionPresenceKey struct{}
+
+// withOptionPresence returns a context in which option decoders can record
+// the fields they set.
+func withOptionPresence(ctx context.Context) (context.Context, *optionPresence) {
+	presence := &optionPresence{fields: map[string]OptionSource{}}
+	return context.WithValue(ctx, optionPresenceKey{}, presence), presence
+}
+
+// markOptionSet records that an option decoder has set the field with the
+// given flag name from the given source.
+func markOptionSet(ctx context.Context, flagName string, source OptionSource) {
+	if presence, ok := ctx.Value(optionPresenceKey{}).(*optionPresence); ok {
+		presence.mutex.Lock()
+		defer presence.mutex.Unlock()
+		presence.fields[flagName] = source
+	}
+}
@@ -0,0 +1,206 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+)
+
+// errProfiling is returned if profiles are requested while the profiles of
+// another run are recorded.
+var errProfiling = errors.New("another run is being profiled")
+
+// profiling is held while profiles are recorded.
+var profiling sync.Mutex
+
+// profilePaths are the paths of the profiles of a run. Profiles with an empty
+// path are not recorded.
+type profilePaths struct {
+	cpu    string
+	heap   string
+	allocs string
+	block  string
+	mutex  string
+	trace  string
+}
+
+// configProfilePaths returns the profile paths of a runner configuration.
+func configProfilePaths(runnerConfig any) profilePaths {
+	var paths profilePaths
+	if p, ok := runnerConfig.(CPUProfiler); ok {
+		paths.cpu = p.CPUProfilePath()
+	}
+	if p, ok := runnerConfig.(MemoryProfiler); ok {
+		paths.heap = p.MemoryProfilePath()
+	}
+	if p, ok := runnerConfig.(AllocsProfiler); ok {
+		paths.allocs = p.AllocsProfilePath()
+	}
+	if p, ok := runnerConfig.(BlockProfiler); ok {
+		paths.block = p.BlockProfilePath()
+	}
+	if p, ok := runnerConfig.(MutexProfiler); ok {
+		paths.mutex = p.MutexProfilePath()
+	}
+	if p, ok := runnerConfig.(TraceProfiler); ok {
+		paths.trace = p.TraceProfilePath()
+	}
+	return paths
+}
+
+// profiles records the profiles of one or more runs. The CPU profile, the
+// execution trace and the block and mutex profiles cover the whole session.
+// The heap and allocation profiles are snapshots taken when the algorithm of
+// a run returns, so that they show the memory held by the run rather than
+// what is left after it.
+//
+// The allocation, block and mutex profiles are cumulative, as the runtime
+// keeps them: they hold the allocations since the process started and the
+// events of every session that enabled them, including earlier profiled runs
+// of a long-lived process. The run alone is shown by comparing a profile to
+// an earlier one with go tool pprof -base.
+type profiles struct {
+	paths         profilePaths
+	cpu           *os.File
+	trace         *os.File
+	mutexFraction int
+	// mutex guards the snapshot, which a later run of a batch overwrites.
+	mutex       sync.Mutex
+	snapshotted bool
+	snapshotErr error
+}
+
+type profilesKey struct{}
+
+// startProfiles starts recording the profiles with a path. It returns
+// errProfiling if the profiles of another run are recorded.
+func startProfiles(paths profilePaths) (p *profiles, err error) {
+	if !profiling.TryLock() {
+		return nil, errProfiling
+	}
+	p = &profiles{paths: paths}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, p.stop())
+		}
+	}()
+	if paths.cpu != "" {
+		if p.cpu, err = startFile(paths.cpu, pprof.StartCPUProfile); err != nil {
+			return p, err
+		}
+	}
+	if paths.trace != "" {
+		if p.trace, err = startFile(paths.trace, trace.Start); err != nil {
+			return p, err
+		}
+	}
+	if paths.block != "" {
+		runtime.SetBlockProfileRate(1)
+	}
+	if paths.mutex != "" {
+		p.mutexFraction = runtime.SetMutexProfileFraction(1)
+	}
+	return p, nil
+}
+
+// startFile creates the file at path and starts recording a profile to it.
+// The file is only returned if the recording started.
+func startFile(path string, start func(io.Writer) error) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := start(f); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	return f, nil
+}
+
+// withProfiles returns a context in which runs take the snapshots of the
+// given profiles.
+func withProfiles(ctx context.Context, p *profiles) context.Context {
+	return context.WithValue(ctx, profilesKey{}, p)
+}
+
+// algorithmReturned takes the heap and allocation profile snapshots of the
+// profiles of the context, if there are any.
+func algorithmReturned(ctx context.Context) {
+	if p, ok := ctx.Value(profilesKey{}).(*profiles); ok {
+		p.snapshot()
+	}
+}
+
+// snapshot writes the heap and allocation profiles.
+func (p *profiles) snapshot() {
+	if p.paths.heap == "" && p.paths.allocs == "" {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// Clean up unused objects from the heap before profiling, so that the heap
+	// profile shows the memory in use.
+	runtime.GC()
+	p.snapshotted = true
+	p.snapshotErr = errors.Join(
+		writeProfile("heap", p.paths.heap),
+		writeProfile("allocs", p.paths.allocs),
+	)
+}
+
+// stop stops recording the profiles and writes the ones that are not written
+// yet. The heap and allocation profiles are taken now if no algorithm
+// returned, e.g. because the input could not be decoded.
+func (p *profiles) stop() error {
+	defer profiling.Unlock()
+	var errs []error
+	if p.paths.block != "" {
+		errs = append(errs, writeProfile("block", p.paths.block))
+		runtime.SetBlockProfileRate(0)
+	}
+	if p.paths.mutex != "" {
+		errs = append(errs, writeProfile("mutex", p.paths.mutex))
+		runtime.SetMutexProfileFraction(p.mutexFraction)
+	}
+	p.mutex.Lock()
+	snapshotted := p.snapshotted
+	p.mutex.Unlock()
+	if !snapshotted {
+		p.snapshot()
+	}
+	p.mutex.Lock()
+	errs = append(errs, p.snapshotErr)
+	p.mutex.Unlock()
+	if p.trace != nil {
+		trace.Stop()
+		errs = append(errs, p.trace.Close())
+	}
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, p.cpu.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// writeProfile writes the named profile to path, if it is not empty.
+func writeProfile(name, path string) (err error) {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tempErr := f.Close()
+		// the first error is more important
+		if err == nil {
+			err = tempErr
+		}
+	}()
+	return pprof.Lookup(name).WriteTo(f, 0)
+}
@@ -0,0 +1,158 @@
+// Package runtest runs algorithms with the runners of the run package
+// in-process, so that they can be tested without building a binary. The input
+// is read from memory and the output is decoded into a schema.Output, which can
+// be compared with the expected output, e.g. a golden file.
+package runtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+// name is the name of the program in the command line of the runners.
+const name = "runtest"
+
+// CLI runs the algorithm once with a CLI runner on the input and returns the
+// decoded output. The runner is configured by args as by the command line of
+// a program, e.g. []string{"-duration", "1s"}, but not by the environment
+// variables of the process. The input path is ignored. Args that do not run
+// the algorithm once on the input or that change how the output is written are
+// rejected: commands other than solve, batch mode, options files, output paths
+// and output compression.
+func CLI[Input, Option, Solution any](
+	ctx context.Context,
+	algorithm run.Algorithm[Input, Option, Solution],
+	input []byte,
+	args []string,
+	options ...run.RunnerOption[run.CLIRunnerConfig, Input, Option, Solution],
+) (schema.Output, error) {
+	runner, err := run.NewCLIRunnerWith(
+		run.CommandLine{Name: name, Args: args, Output: io.Discard},
+		algorithm,
+		options...,
+	)
+	if err != nil {
+		return schema.Output{}, err
+	}
+	if err := checkCLIConfig(runner.RunnerConfig()); err != nil {
+		return schema.Output{}, err
+	}
+	return Run(ctx, runner, input)
+}
+
+// checkCLIConfig returns an error if the configuration of a CLI runner does
+// not run the algorithm once on the input of CLI.
+func checkCLIConfig(cfg run.CLIRunnerConfig) error {
+	switch {
+	case cfg.Command() != run.CommandSolve:
+		return fmt.Errorf("command %s is not supported, only %s", cfg.Command(), run.CommandSolve)
+	case cfg.Runner.Input.Dir != "" || cfg.Runner.Input.Lines:
+		return errors.New("batch mode is not supported, run every input instead")
+	case cfg.Runner.Options.Path != "":
+		return errors.New("options files are not supported, pass the options as args instead")
+	case cfg.Runner.Output.Path != "":
+		return errors.New("output paths are not supported, the output is returned instead")
+	case cfg.Runner.Output.Compression != "":
+		return errors.New("output compression is not supported, the output is returned decoded")
+	}
+	return nil
+}
+
+// Run runs the runner once on the input and returns the decoded output. It
+// replaces the IOProducer of the runner, so that the input is read from
+// memory and the output is written to memory. If the runner writes more than
+// one output, e.g. because all solutions are written, the last one is
+// returned.
+func Run[RunnerConfig, Input, Option, Solution any](
+	ctx context.Context,
+	runner run.Runner[RunnerConfig, Input, Option, Solution],
+	input []byte,
+) (schema.Output, error) {
+	var output bytes.Buffer
+	runner.SetIOProducer(
+		func(context.Context, RunnerConfig) (run.IOData, error) {
+			return run.NewIOData(bytes.NewReader(input), nil, &output)
+		},
+	)
+	if err := runner.Run(ctx); err != nil {
+		return schema.Output{}, err
+	}
+	return decodeOutput(&output)
+}
+
+// HTTP runs the algorithm once with an HTTP runner on the input and returns
+// the decoded output. The input is posted to the handler of the runner, without
+// starting a server, and the options are given as the query parameters of the
+// request, e.g. url.Values{"duration": {"1s"}}. The runner is not configured
+// by the command line or the environment variables of the process. Responses
+// other than 200 OK are returned as errors.
+func HTTP[Input, Option, Solution any](
+	ctx context.Context,
+	algorithm run.Algorithm[Input, Option, Solution],
+	input []byte,
+	query url.Values,
+	options ...run.HTTPRunnerOption[Input, Option, Solution],
+) (schema.Output, error) {
+	runner, err := run.NewHTTPRunnerWith(
+		run.CommandLine{Name: name, Output: io.Discard},
+		algorithm,
+		options...,
+	)
+	if err != nil {
+		return schema.Output{}, err
+	}
+	handler, ok := runner.(http.Handler)
+	if !ok {
+		return schema.Output{}, errors.New("runner is not an http.Handler")
+	}
+
+	req := httptest.NewRequest(
+		http.MethodPost, "/?"+query.Encode(), bytes.NewReader(input),
+	).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	response := recorder.Result()
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return schema.Output{}, fmt.Errorf(
+			"%s: %s", response.Status, strings.TrimSpace(string(body)),
+		)
+	}
+	return decodeOutput(response.Body)
+}
+
+// decodeOutput decodes the last of the JSON outputs read from reader.
+func decodeOutput(reader io.Reader) (schema.Output, error) {
+	decoder := json.NewDecoder(reader)
+	var output schema.Output
+	decoded := false
+	for {
+		var next schema.Output
+		err := decoder.Decode(&next)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return schema.Output{}, fmt.Errorf("decode output: %w", err)
+		}
+		output, decoded = next, true
+	}
+	if !decoded {
+		return schema.Output{}, errors.New("runner wrote no output")
+	}
+	return output, nil
+}
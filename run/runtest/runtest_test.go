@@ -0,0 +1,144 @@
+package runtest_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/runtest"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+type input struct {
+	Message string `json:"message"`
+}
+
+type option struct {
+	Suffix string `json:"suffix" default:" World!"`
+}
+
+type output struct {
+	Message string `json:"message"`
+}
+
+func algorithm(
+	_ context.Context, input input, opts option, solutions chan<- schema.Output,
+) error {
+	if input.Message == "fail" {
+		return errors.New("failed")
+	}
+	solutions <- schema.NewOutput(opts, output{Message: input.Message + opts.Suffix})
+	return nil
+}
+
+// message returns the message of the first solution of the output.
+func message(t *testing.T, output schema.Output) any {
+	if len(output.Solutions) != 1 {
+		t.Fatalf("got %v; want %v", len(output.Solutions), 1)
+	}
+	solution, ok := output.Solutions[0].(map[string]any)
+	if !ok {
+		t.Fatalf("got %T; want %T", output.Solutions[0], map[string]any{})
+	}
+	return solution["message"]
+}
+
+func TestCLI(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "Hello World!"},
+		{[]string{"-suffix", " Gophers!"}, "Hello Gophers!"},
+	}
+	for _, test := range tests {
+		output, err := runtest.CLI(
+			context.Background(), algorithm, []byte(`{"message": "Hello"}`), test.args,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := message(t, output); got != test.want {
+			t.Errorf("got %v; want %v", got, test.want)
+		}
+	}
+}
+
+func TestCLIError(t *testing.T) {
+	_, err := runtest.CLI(
+		context.Background(), algorithm, []byte(`{"message": "fail"}`), nil,
+	)
+	var runErr *run.Error
+	if !errors.As(err, &runErr) || runErr.Code != run.ErrorCodeAlgorithm {
+		t.Errorf("got %v; want %v", err, run.ErrorCodeAlgorithm)
+	}
+}
+
+func TestCLIUnsupportedArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"validate"},
+		{"-runner.input.dir", "inputs"},
+		{"-runner.input.lines"},
+		{"-runner.options.path", "options.json"},
+		{"-runner.output.path", "output.json.gz"},
+		{"-runner.output.compression", "zstd"},
+	} {
+		_, err := runtest.CLI(
+			context.Background(), algorithm, []byte(`{"message": "Hello"}`), args,
+		)
+		if err == nil {
+			t.Errorf("got %v; want an error for %v", err, args)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	runner, err := run.NewCLIRunnerWith(
+		run.CommandLine{Args: []string{"-runner.output.solutions", "all"}},
+		algorithm,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := runtest.Run(context.Background(), runner, []byte(`{"message": "Hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"suffix": " World!"}
+	if !reflect.DeepEqual(output.Options, want) {
+		t.Errorf("got %v; want %v", output.Options, want)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	tests := []struct {
+		query url.Values
+		want  string
+	}{
+		{nil, "Hello World!"},
+		{url.Values{"suffix": {" Gophers!"}}, "Hello Gophers!"},
+	}
+	for _, test := range tests {
+		output, err := runtest.HTTP(
+			context.Background(), algorithm, []byte(`{"message": "Hello"}`), test.query,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := message(t, output); got != test.want {
+			t.Errorf("got %v; want %v", got, test.want)
+		}
+	}
+}
+
+func TestHTTPInvalidInput(t *testing.T) {
+	_, err := runtest.HTTP(
+		context.Background(), algorithm, []byte(`{"message": 1}`), nil,
+	)
+	if err == nil {
+		t.Errorf("got %v; want an error", err)
+	}
+}
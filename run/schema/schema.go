@@ -26,15 +26,19 @@ func NewOutput[Solution any](options any, solutions ...Solution) Output {
 	for i, solution := range solutions {
 		solutionsAny[i] = solution
 	}
-	// collect known dependency versions
-	dependencies := collectKnownDependencies()
 	return Output{
 		Solutions: solutionsAny,
 		Options:   options,
-		Version:   dependencies,
+		Version:   NewVersion(),
 	}
 }
 
+// NewVersion returns the versions of the known dependencies, such as the sdk,
+// the running binary was built with.
+func NewVersion() Version {
+	return collectKnownDependencies()
+}
+
 // knownDependencies is a list of known dependencies that we want to put in the
 // version of the output.
 var knownDependencies = []struct {
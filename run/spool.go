@@ -0,0 +1,70 @@
+package run
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// InputMemoryLimit is the size in bytes up to which an input is held in
+// memory by NewIOData. Larger inputs are spooled to a temporary file, which is
+// removed once the input is decoded.
+var InputMemoryLimit int64 = 32 << 20
+
+// spool holds data that is read more than once, e.g. by the validator and the
+// decoder. Data up to limit bytes is held in memory, the rest of it is written
+// to a temporary file.
+type spool struct {
+	limit int64
+	mem   bytes.Buffer
+	file  *os.File
+	size  int64
+}
+
+// newSpool reads all of reader into a spool.
+func newSpool(reader io.Reader, limit int64) (s *spool, err error) {
+	s = &spool{limit: limit}
+	defer func() {
+		if err != nil {
+			_ = s.Close()
+		}
+	}()
+	// read one byte more than the limit to know whether it is exceeded.
+	n, err := s.mem.ReadFrom(io.LimitReader(reader, limit+1))
+	s.size = n
+	if err != nil || n <= limit {
+		return s, err
+	}
+	s.file, err = os.CreateTemp("", "nextmv-input-*")
+	if err != nil {
+		return s, err
+	}
+	if _, err := s.mem.WriteTo(s.file); err != nil {
+		return s, err
+	}
+	n, err = io.Copy(s.file, reader)
+	s.size += n
+	return s, err
+}
+
+// reader returns a new reader of the data, which reads it from the start.
+func (s *spool) reader() io.Reader {
+	if s.file != nil {
+		return io.NewSectionReader(s.file, 0, s.size)
+	}
+	return bytes.NewReader(s.mem.Bytes())
+}
+
+// Close removes the temporary file, if any.
+func (s *spool) Close() error {
+	s.mem = bytes.Buffer{}
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if tempErr := os.Remove(s.file.Name()); err == nil {
+		err = tempErr
+	}
+	s.file = nil
+	return err
+}
@@ -0,0 +1,281 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nextmv-io/sdk/run/schema"
+	"github.com/nextmv-io/sdk/run/statistics"
+)
+
+// Valuer is the interface a solution can implement to return its value, e.g.
+// the objective value. The values of all solutions of a run form the value
+// series of the statistics.
+type Valuer interface {
+	Value() float64
+}
+
+// AddIterations adds n to the number of iterations of the run. The number of
+// iterations is reported in the run statistics of the output.
+func AddIterations(ctx context.Context, n int) {
+	if r, ok := ctx.Value(statisticsKey{}).(*statisticsRecorder); ok {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.iterations += n
+	}
+}
+
+// RecordSeriesValue adds a data point to the custom series with the given
+// name. The x value of the point is the time since the start of the run in
+// seconds. The custom series are reported in the series data of the output.
+func RecordSeriesValue(ctx context.Context, name string, value float64) {
+	r, ok := ctx.Value(statisticsKey{}).(*statisticsRecorder)
+	if !ok {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	index, ok := r.seriesIndex[name]
+	if !ok {
+		index = len(r.custom)
+		r.seriesIndex[name] = index
+		r.custom = append(r.custom, statistics.Series{Name: name})
+	}
+	r.custom[index].DataPoints = append(r.custom[index].DataPoints, statistics.DataPoint{
+		X: statistics.Float64(r.elapsed()),
+		Y: statistics.Float64(value),
+	})
+}
+
+type statisticsKey struct{}
+
+// statisticsRecorder records the statistics of a run.
+type statisticsRecorder struct {
+	mutex       sync.Mutex
+	start       time.Time
+	iterations  int
+	values      statistics.Series
+	custom      []statistics.Series
+	seriesIndex map[string]int
+	// exported are the keys of the data of the run that are exported.
+	exported map[string]bool
+}
+
+// withStatistics returns a context in which the statistics of a run starting
+// at start are recorded.
+func withStatistics(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, statisticsKey{}, &statisticsRecorder{
+		start:       start,
+		values:      statistics.Series{Name: "value"},
+		seriesIndex: map[string]int{},
+		exported:    map[string]bool{},
+	})
+}
+
+// elapsed returns the time since the start of the run in seconds.
+func (r *statisticsRecorder) elapsed() float64 {
+	return time.Since(r.start).Seconds()
+}
+
+// collectStatistics forwards the solutions of an algorithm and fills in the
+// statistics of solutions of type schema.Output: the run duration and
+// iterations, the time to the solution, its value and the value series, and
+// the custom series and the exported data of the run. Statistics set by the
+// algorithm are kept. The statistics are taken when a solution is received,
+// so they may include records the algorithm made right after sending it.
+func collectStatistics[Solution any](
+	ctx context.Context, found <-chan Solution,
+) <-chan Solution {
+	r, ok := ctx.Value(statisticsKey{}).(*statisticsRecorder)
+	if !ok {
+		return found
+	}
+	solutions := make(chan Solution)
+	go func() {
+		defer close(solutions)
+		for solution := range found {
+			if annotated, ok := r.annotate(ctx, solution).(Solution); ok {
+				solution = annotated
+			}
+			solutions <- solution
+		}
+	}()
+	return solutions
+}
+
+// annotate records the value of a solution and fills in its statistics.
+func (r *statisticsRecorder) annotate(ctx context.Context, solution any) any {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	elapsed := r.elapsed()
+	value, hasValue := solutionValue(solution)
+	if hasValue {
+		r.values.DataPoints = append(r.values.DataPoints, statistics.DataPoint{
+			X: statistics.Float64(elapsed),
+			Y: statistics.Float64(value),
+		})
+	}
+
+	// the output of the algorithm is not modified, it may still be in use by
+	// the algorithm, so its statistics are filled in on a copy.
+	var output schema.Output
+	switch s := solution.(type) {
+	case schema.Output:
+		output = s
+	case *schema.Output:
+		if s == nil {
+			return solution
+		}
+		output = *s
+	default:
+		return solution
+	}
+	output.Statistics = copyStatistics(output.Statistics)
+	r.fillRun(ctx, output.Statistics, elapsed, value, hasValue)
+	r.fillSeries(output.Statistics)
+	return r.result(solution, &output)
+}
+
+// copyStatistics returns a copy of the statistics whose sections can be filled
+// in without modifying the given ones. It returns new statistics if stats is
+// nil.
+func copyStatistics(stats *statistics.Statistics) *statistics.Statistics {
+	if stats == nil {
+		return statistics.NewStatistics()
+	}
+	copied := *stats
+	if stats.Run != nil {
+		run := *stats.Run
+		copied.Run = &run
+	}
+	if stats.Result != nil {
+		result := *stats.Result
+		copied.Result = &result
+	}
+	if stats.SeriesData != nil {
+		seriesData := *stats.SeriesData
+		copied.SeriesData = &seriesData
+	}
+	return &copied
+}
+
+// fillRun fills in the run and result statistics that are not set. The run
+// and result durations are the time from the start of the run until the
+// solution reached the recorder, not the time the algorithm took to find it,
+// so they include the time the solution waited to be received.
+func (r *statisticsRecorder) fillRun(
+	ctx context.Context,
+	stats *statistics.Statistics,
+	elapsed, value float64,
+	hasValue bool,
+) {
+	if stats.Run == nil {
+		stats.Run = &statistics.Run{}
+	}
+	if stats.Run.Duration == nil {
+		stats.Run.Duration = &elapsed
+	}
+	if exported := r.exportedData(ctx); len(exported) > 0 {
+		stats.Run.Custom = mergeCustom(stats.Run.Custom, exported)
+	}
+	if stats.Run.Iterations == nil && r.iterations > 0 {
+		iterations := r.iterations
+		stats.Run.Iterations = &iterations
+	}
+	if stats.Result == nil {
+		stats.Result = &statistics.Result{}
+	}
+	if stats.Result.Duration == nil {
+		stats.Result.Duration = &elapsed
+	}
+	if stats.Result.Value == nil && hasValue {
+		v := statistics.Float64(value)
+		stats.Result.Value = &v
+	}
+}
+
+// mergeCustom adds the exported data to the custom run statistics of the
+// algorithm. The values of the algorithm win over exported ones of the same
+// key. Custom statistics that are not a map are kept as they are.
+func mergeCustom(custom any, exported map[string]any) any {
+	if custom == nil {
+		return exported
+	}
+	values, ok := custom.(map[string]any)
+	if !ok {
+		return custom
+	}
+	// the map of the algorithm is not modified, it may be shared by its
+	// solutions.
+	merged := make(map[string]any, len(values)+len(exported))
+	for key, value := range exported {
+		merged[key] = value
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	return merged
+}
+
+// fillSeries fills in the value series and the custom series that are not
+// set.
+func (r *statisticsRecorder) fillSeries(stats *statistics.Statistics) {
+	if len(r.values.DataPoints) == 0 && len(r.custom) == 0 {
+		return
+	}
+	if stats.SeriesData == nil {
+		stats.SeriesData = &statistics.SeriesData{}
+	}
+	if len(stats.SeriesData.Value.DataPoints) == 0 && len(r.values.DataPoints) > 0 {
+		stats.SeriesData.Value = copySeries(r.values)
+	}
+	if len(stats.SeriesData.Custom) == 0 && len(r.custom) > 0 {
+		stats.SeriesData.Custom = make([]statistics.Series, len(r.custom))
+		for i, series := range r.custom {
+			stats.SeriesData.Custom[i] = copySeries(series)
+		}
+	}
+}
+
+// result returns the annotated output in the type of the solution.
+func (r *statisticsRecorder) result(solution any, output *schema.Output) any {
+	if _, ok := solution.(schema.Output); ok {
+		return *output
+	}
+	return output
+}
+
+// solutionValue returns the value of a solution that implements Valuer. For a
+// schema.Output, the value of its last solution is returned.
+func solutionValue(solution any) (float64, bool) {
+	switch s := solution.(type) {
+	case Valuer:
+		return s.Value(), true
+	case schema.Output:
+		return lastSolutionValue(s.Solutions)
+	case *schema.Output:
+		if s != nil {
+			return lastSolutionValue(s.Solutions)
+		}
+	}
+	return 0, false
+}
+
+func lastSolutionValue(solutions []any) (float64, bool) {
+	if len(solutions) == 0 {
+		return 0, false
+	}
+	if valuer, ok := solutions[len(solutions)-1].(Valuer); ok {
+		return valuer.Value(), true
+	}
+	return 0, false
+}
+
+// copySeries returns a copy of a series that does not share its data points.
+func copySeries(series statistics.Series) statistics.Series {
+	return statistics.Series{
+		Name:       series.Name,
+		DataPoints: append([]statistics.DataPoint{}, series.DataPoints...),
+	}
+}
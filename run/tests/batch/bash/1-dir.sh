@@ -0,0 +1,11 @@
+# Run every file in a directory and write one output per input file.
+./main.exe \
+    -runner.input.dir inputs \
+    -runner.output.dir outputs \
+    -runner.batch.workers 2
+echo "exit code: $?"
+for f in outputs/*; do
+    echo "$f"
+    cat "$f"
+done
+rm -rf outputs
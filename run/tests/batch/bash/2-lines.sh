@@ -0,0 +1,12 @@
+# Run every line of stdin and write one JSON line per input. Failed inputs are
+# written as an error line and summarized at the end.
+printf '%s\n' \
+    '{"message": "Hello"}' \
+    '{"message": 1}' \
+    '' \
+    '{"message": "fail"}' \
+    '{"message": "Hola"}' |
+    ./main.exe -runner.input.lines -runner.batch.workers 3 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
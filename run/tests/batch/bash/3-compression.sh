@@ -0,0 +1,10 @@
+# Compressed output is rejected in batch mode, whether it is configured or
+# implied by the extension of the output path.
+printf '%s\n' '{"message": "Hello"}' |
+    ./main.exe -runner.input.lines -runner.output.compression zstd 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+./main.exe -runner.input.dir inputs -runner.output.path out.zst 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm -f stderr.txt out.zst
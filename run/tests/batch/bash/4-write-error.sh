@@ -0,0 +1,6 @@
+# An output that cannot be written fails the batch, but not its inputs.
+printf '%s\n' '{"message": "Hello"}' '{"message": "Hola"}' |
+    ./main.exe -runner.input.lines -runner.output.path /dev/full 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
@@ -0,0 +1,5 @@
+# Only the last solution of an input is written as a JSON line, even if all
+# solutions are configured, so that lines and inputs stay aligned.
+printf '%s\n' '{"message": "Hello"}' '{"message": "Hola"}' |
+    ./main.exe -runner.input.lines -runner.output.solutions all
+echo "exit code: $?"
@@ -0,0 +1,7 @@
+# The output dir cannot be the input dir, the outputs would overwrite the
+# inputs before they are read.
+./main.exe -runner.input.dir inputs -runner.output.dir ./inputs/ 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
+ls inputs
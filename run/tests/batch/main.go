@@ -0,0 +1,43 @@
+// package main holds the implementation of a runner that is run in batch mode.
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func main() {
+	err := run.NewCLIRunner(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Suffix string `json:"suffix" default:" World!" usage:"Suffix of the message."`
+}
+
+type output struct {
+	Message string `json:"message"`
+}
+
+func algorithm(
+	_ context.Context, input input, opts option, solutions chan<- output,
+) error {
+	if input.Message == "fail" {
+		return errors.New("the algorithm failed on purpose")
+	}
+	// send an intermediate solution before the final one
+	solutions <- output{Message: input.Message}
+	solutions <- output{Message: input.Message + opts.Suffix}
+	return nil
+}
@@ -0,0 +1,12 @@
+# a valid input.
+go run main.go validate -runner.input.path inputs/1-valid.json
+echo "exit code $?"
+# an invalid input, the error is reported with the exit code of invalid input.
+echo '{"message": 1}' | go run main.go validate 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+# every input of a directory.
+go run main.go validate -runner.input.dir inputs 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
@@ -0,0 +1 @@
+go run main.go schema
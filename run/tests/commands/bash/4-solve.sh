@@ -0,0 +1,7 @@
+# solve is the default command.
+go run main.go solve -runner.input.path inputs/1-valid.json -duration 0s | jq .solutions
+# unknown commands are rejected.
+go run main.go solver 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
@@ -0,0 +1,8 @@
+# the options are described as a markdown table.
+go run main.go options
+# options that violate their constraints are rejected before the algorithm
+# runs, with the exit code of invalid options.
+go run main.go solve -runner.input.path inputs/1-valid.json -duration 1m 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm stderr.txt
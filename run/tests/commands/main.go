@@ -0,0 +1,40 @@
+// package main holds the implementation of a runner example with commands.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.CLI(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		// exit with a distinct code per kind of failure, e.g. 3 for invalid
+		// input.
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Duration time.Duration `json:"duration" default:"1s" max:"10s" usage:"Sleep duration."`
+}
+
+type output struct {
+	Message string `json:"message"`
+}
+
+func algorithm(_ context.Context, input input, opts option) (schema.Output, error) {
+	// sleep for the specified duration, 1s by default as defined via go tags
+	time.Sleep(opts.Duration)
+	return schema.NewOutput(opts, output{Message: input.Message + " World!"}), nil
+}
@@ -0,0 +1,4 @@
+# Compressed inputs are detected by their magic bytes.
+for f in input.json.gz input.json.zst input.json.bz2; do
+    ./main.exe -runner.input.path $f
+done
@@ -0,0 +1,19 @@
+# Outputs are compressed by the extension of the output path or by a flag. The
+# compressed outputs are read back as inputs.
+echo '{"message": "Hello"}' | ./main.exe -runner.output.path output.json.zst
+echo '{"message": "Hello"}' | ./main.exe -runner.output.path output.json.gz
+echo '{"message": "Hello"}' | ./main.exe -runner.output.path output.json \
+    -runner.output.compression zstd
+for f in output.json.zst output.json.gz output.json; do
+    head -c 4 $f | od -An -tx1
+    ./main.exe -runner.input.path $f
+done
+gunzip -c output.json.gz
+# An explicit none compression overrides the extension of the output path.
+echo '{"message": "Hello"}' | ./main.exe -runner.output.path output.json.gz \
+    -runner.output.compression none
+head -c 4 output.json.gz | od -An -tx1
+cat output.json.gz
+rm output.json.zst output.json.gz output.json
+echo '{"message": "Hello"}' | ./main.exe -runner.output.compression brotli 2>&1 |
+    sed -E 's/^[0-9/]+ [0-9:]+ //'
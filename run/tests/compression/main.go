@@ -0,0 +1,29 @@
+// package main holds the implementation of a runner that reads compressed
+// inputs and writes compressed outputs.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func main() {
+	err := run.CLI(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Message string `json:"message"`
+}
+
+type option struct{}
+
+func algorithm(_ context.Context, input input, _ option) (input, error) {
+	return input, nil
+}
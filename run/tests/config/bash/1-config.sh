@@ -0,0 +1,4 @@
+# the runner configuration and the options are read from the config file.
+go run main.go -runner.config config.yaml
+go run main.go -runner.config config.toml
+GREETER_RUNNER_CONFIG=config.json go run main.go
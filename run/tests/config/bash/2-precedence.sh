@@ -0,0 +1,5 @@
+# environment variables take precedence over the config file and flags over
+# environment variables.
+GREETER_GREETING=Hello GREETER_REPEAT=3 go run main.go -runner.config config.yaml -repeat 4
+# variables without the prefix are not read.
+GREETING=Hello go run main.go -runner.config config.yaml | jq .message
@@ -0,0 +1,11 @@
+echo 'duration: soon' > invalid.yaml
+go run main.go -runner.config invalid.yaml 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm invalid.yaml stderr.txt
+# keys that match no option or runner configuration are reported.
+printf 'greting: Hi\nrunner:\n  input:\n    path: input.json\n    pth: input.json\n' > unknown.yaml
+go run main.go -runner.config unknown.yaml 2> stderr.txt
+echo "exit code $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm unknown.yaml stderr.txt
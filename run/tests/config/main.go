@@ -0,0 +1,51 @@
+// package main holds the implementation of a runner that is configured by a
+// config file and environment variables with an application prefix.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func main() {
+	// read GREETER_DURATION instead of DURATION, and so on.
+	run.EnvPrefix = "GREETER"
+	err := run.CLI(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Message string `json:"message"`
+}
+
+type option struct {
+	Greeting string        `json:"greeting" default:"Hello" usage:"The greeting."`
+	Repeat   int           `json:"repeat" default:"1" usage:"Number of repetitions."`
+	Duration time.Duration `json:"duration" default:"1s" usage:"Sleep duration."`
+	Names    []string      `json:"names" usage:"Names to greet."`
+}
+
+type output struct {
+	Message  string                      `json:"message"`
+	Repeat   int                         `json:"repeat"`
+	Duration time.Duration               `json:"duration"`
+	Names    []string                    `json:"names"`
+	Sources  map[string]run.OptionSource `json:"sources"`
+}
+
+func algorithm(ctx context.Context, input input, opts option) (output, error) {
+	return output{
+		Message:  opts.Greeting + " " + input.Message,
+		Repeat:   opts.Repeat,
+		Duration: opts.Duration,
+		Names:    opts.Names,
+		Sources:  run.OptionSources(ctx),
+	}, nil
+}
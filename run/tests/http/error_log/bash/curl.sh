@@ -5,7 +5,8 @@ sleep 0.5
 go run main.go > /dev/null 2>&1 &
 sleep 3.5
 PID2=$(lsof -i -P | grep LISTEN | grep :9002 | tr -s ' ' | cut -d ' ' -f 2)
-curl -s -X POST "http://localhost:9002?duration=500000000" -H 'Content-Type: application/json' -d '{'
+curl -s -w "\n%{http_code}\n" -X POST "http://localhost:9002?duration=500000000" -H 'Content-Type: application/json' -d '{'
+curl -s -w "\n%{http_code}\n" -X POST "http://localhost:9002?duration=0" -H 'Content-Type: application/json' -d '{"message": 1}'
 if false; then
 curl -s -X POST "http://localhost:9000?duration=500000000" -H 'Content-Type: application/json' -d '{'
 fi
@@ -0,0 +1,29 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9009 | tr -s ' ' | cut -d ' ' -f 2)
+# JSON is the default
+curl -s -X POST "http://localhost:9009" -H 'Content-Type: application/json' -d '{"message":"Hello"}'
+# XML in, XML out
+curl -s -X POST "http://localhost:9009" -H 'Content-Type: application/xml' -H 'Accept: application/xml' -d '<input><message>Hello</message></input>'
+echo
+# the encoder with the highest quality wins
+curl -s -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Accept: application/xml;q=0.5, application/json' -d '{"message":"Hello"}'
+curl -s -D - -o /dev/null -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Accept: application/*' -d '{"message":"Hello"}' | grep -i "^content-type"
+# unsupported formats are rejected
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9009" -H 'Content-Type: text/csv' -d 'message'
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Accept: text/csv' -d '{"message":"Hello"}'
+# compressed requests and responses
+echo '{"message":"Hello gzip"}' | gzip -c | curl -s -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Content-Encoding: gzip' --data-binary @-
+curl -s -D headers.txt -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Accept-Encoding: gzip' -d '{"message":"Hello"}' | gunzip -c
+grep -i "^content-encoding" headers.txt
+curl -s -D headers.txt -o body.zst -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Accept-Encoding: gzip;q=0.5, zstd' -d '{"message":"Hello"}'
+grep -i "^content-encoding" headers.txt
+head -c 4 body.zst | od -An -tx1
+rm headers.txt body.zst
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9009" -H 'Content-Type: application/json' -H 'Content-Encoding: br' -d '{"message":"Hello"}'
+kill $PID2 > /dev/null 2>&1
+exit 0
@@ -0,0 +1,48 @@
+// package main holds the implementation of a runner that negotiates the
+// formats of input and output.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/decode"
+	"github.com/nextmv-io/sdk/run/encode"
+	"github.com/nextmv-io/sdk/run/http"
+)
+
+func main() {
+	err := run.HTTP(algorithm,
+		// listen on port 9009
+		run.SetAddr[input, option, output](":9009"),
+		// accept XML next to JSON
+		http.AddDecoder[input, option, output]("application/xml", decode.XML()),
+		// answer with XML if the client asks for it
+		http.AddEncoder[input, option, output]("application/xml", encode.XML()),
+		// override the default logger
+		run.SetLogger[input, option, output](
+			log.New(os.Stdout, "[demo] - ", log.Lshortfile),
+		),
+	).Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type input struct {
+	Message string `json:"message" xml:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Suffix string `json:"suffix" default:" World!" usage:"Suffix of the message."`
+}
+
+type output struct {
+	Message string `json:"message" xml:"message"`
+}
+
+func algorithm(_ context.Context, input input, opts option) (output, error) {
+	return output{Message: input.Message + opts.Suffix}, nil
+}
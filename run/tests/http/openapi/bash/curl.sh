@@ -0,0 +1,18 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9011 | tr -s ' ' | cut -d ' ' -f 2)
+curl -s "http://localhost:9011/openapi.json" | jq .
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9011/openapi.json"
+# the options are described as JSON or, if asked for, as markdown.
+curl -s "http://localhost:9011/options" | jq .
+curl -s "http://localhost:9011/options" -H "Accept: text/markdown"
+# options that violate their constraints fail the run.
+ID=$(curl -s -X POST "http://localhost:9011?strategy=slow&duration=0" -d '{"message":"Hello"}')
+sleep 0.5
+curl -s "http://localhost:9011/runs/$ID" | jq '{status, error}'
+kill $PID2 > /dev/null 2>&1
+exit 0
@@ -0,0 +1,50 @@
+// package main holds the implementation of a runner that serves its OpenAPI
+// document.
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.HTTP(algorithm,
+		// listen on port 9011
+		run.SetAddr[input, option, schema.Output](":9011"),
+		// runs are asynchronous, the result is polled or sent to a callback
+		run.SetHTTPRequestHandler[input, option, schema.Output](
+			run.AsyncHTTPRequestHandler(run.RequireCallback(false)),
+		),
+	).Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type greeting struct {
+	Suffix string `json:"suffix" schema:"suffix" default:" World!" usage:"Text after the message."`
+}
+
+type option struct {
+	Duration time.Duration `json:"duration" default:"1s" max:"1m" usage:"Sleep duration."`
+	Strategy string        `json:"strategy" default:"fast" enum:"fast,thorough" usage:"Search strategy."`
+	Greeting greeting      `json:"greeting" schema:"greeting"`
+}
+
+type output struct {
+	Message string `json:"message"`
+}
+
+func algorithm(_ context.Context, input input, opts option) (schema.Output, error) {
+	// sleep for the specified duration, 1s by default as defined via go tags
+	time.Sleep(opts.Duration)
+	return schema.NewOutput(opts, output{Message: input.Message + opts.Greeting.Suffix}), nil
+}
@@ -0,0 +1,16 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9005 | tr -s ' ' | cut -d ' ' -f 2)
+ID=$(curl -s -X POST "http://localhost:9005?duration=500000000" -H 'Content-Type: application/json' -d '{"message":"Hello"}')
+curl -s "http://localhost:9005/runs/$ID" | jq .status
+curl -s "http://localhost:9005/runs/$ID/result"
+sleep 1
+curl -s "http://localhost:9005/runs/$ID" | jq .status
+curl -s "http://localhost:9005/runs/$ID/result" | jq
+curl -s -o /dev/null -w "%{http_code}\n" "http://localhost:9005/runs/unknown"
+kill $PID2 > /dev/null 2>&1
+exit 0
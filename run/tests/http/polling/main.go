@@ -0,0 +1,51 @@
+// package main holds the implementation of an asynchronous runner example
+// whose results are polled instead of being sent to a callback url.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.HTTP(algorithm,
+		// listen on port 9005
+		run.SetAddr[input, option, schema.Output](":9005"),
+		// override the default logger
+		run.SetLogger[input, option, schema.Output](
+			log.New(os.Stdout, "[demo] - ", log.LstdFlags),
+		),
+		// run asynchronously without a callback url, the result is polled
+		run.SetHTTPRequestHandler[input, option, schema.Output](
+			run.AsyncHTTPRequestHandler(
+				run.RequireCallback(false),
+			),
+		),
+	).Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Duration time.Duration `json:"duration" default:"1s" usage:"Sleep duration."`
+}
+
+type output struct {
+	Message string `json:"message"`
+}
+
+func algorithm(_ context.Context, input input, opts option) (schema.Output, error) {
+	// sleep for the specified duration, 1s by default as defined via go tags
+	time.Sleep(opts.Duration)
+	return schema.NewOutput(opts, output{Message: input.Message + " World!"}), nil
+}
@@ -0,0 +1,23 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9007 | tr -s ' ' | cut -d ' ' -f 2)
+curl -s "http://localhost:9007/healthz"
+curl -s "http://localhost:9007/readyz"
+curl -s "http://localhost:9007/info" | jq .config.runner.http
+curl -s -X POST "http://localhost:9007?duration=1000000000" -H 'Content-Type: application/json' -d '{"message":"Hello"}' > /dev/null &
+PID3=$!
+sleep 0.2
+# the only slot is taken
+curl -s -w "%{http_code}\n" "http://localhost:9007/readyz"
+wait $PID3
+curl -s -w "%{http_code}\n" "http://localhost:9007/readyz"
+# only POST requests to the root path start runs
+curl -s -w "%{http_code}\n" "http://localhost:9007/"
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9007/solve" -d '{"message":"Hello"}'
+curl -s "http://localhost:9007/metrics" | grep -E "^nextmv_(http_requests_total|active_runs|run_phase_duration_seconds_count)"
+kill $PID2 > /dev/null 2>&1
+exit 0
@@ -0,0 +1,25 @@
+DIR=$(mktemp -d)
+go run main.go -runner.http.profiledir $DIR -runner.http.maxparallel 2 > /dev/null 2>&1 &
+sleep 3.5
+PID=$(lsof -i -P | grep LISTEN | grep :9010 | tr -s ' ' | cut -d ' ' -f 2)
+# profile a single request.
+curl -s -X POST "http://localhost:9010" -H 'X-Nextmv-Profile: cpu, heap, trace' \
+    -H 'Content-Type: application/json' -d '{"message":"Hello"}' | jq .solutions
+ls $DIR
+# requests without the header are not profiled.
+curl -s -X POST "http://localhost:9010" -d '{"message":"Hello"}' | jq .solutions
+ls $DIR | wc -l
+# unknown profiles are rejected.
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9010" -H 'X-Nextmv-Profile: disk' \
+    -d '{"message":"Hello"}'
+# only one run is profiled at a time, other requests for profiles conflict.
+curl -s -o /dev/null -X POST "http://localhost:9010?duration=1000000000" \
+    -H 'X-Nextmv-Profile: cpu' -d '{"message":"Hello"}' &
+PROFILED=$!
+sleep 0.3
+curl -s -w "%{http_code}\n" -X POST "http://localhost:9010" -H 'X-Nextmv-Profile: heap' \
+    -d '{"message":"Hello"}'
+wait $PROFILED
+kill $PID > /dev/null 2>&1
+rm -rf $DIR
+exit 0
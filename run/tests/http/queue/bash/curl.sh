@@ -0,0 +1,18 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9006 | tr -s ' ' | cut -d ' ' -f 2)
+curl -s -X POST "http://localhost:9006?duration=1000000000" -H 'Content-Type: application/json' -d '{"message":"Hello one"}' > /dev/null 2>&1 &
+sleep 0.1
+# waits in the queue until the first request is done
+curl -s -X POST "http://localhost:9006?duration=100000000" -H 'Content-Type: application/json' -d '{"message":"Hello two"}' | jq .solutions &
+PID3=$!
+sleep 0.1
+# the queue is full, so this request is rejected
+curl -s -X POST "http://localhost:9006?duration=100000000" -H 'Content-Type: application/json' -d '{"message":"Hello three"}'
+wait $PID3
+kill $PID2 > /dev/null 2>&1
+exit 0
@@ -0,0 +1,14 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9004 | tr -s ' ' | cut -d ' ' -f 2)
+curl -s -X POST "http://localhost:9004?duration=2000000000" -H 'Content-Type: application/json' -d '{"message":"Hello"}' | jq &
+PID3=$!
+sleep 0.5
+# the run in flight finishes although the server is asked to stop
+kill $PID2 > /dev/null 2>&1
+wait $PID3
+exit 0
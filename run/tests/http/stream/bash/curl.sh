@@ -0,0 +1,17 @@
+if false; then
+go run main.go
+fi
+sleep 0.5
+go run main.go > /dev/null 2>&1 &
+sleep 3.5
+PID2=$(lsof -i -P | grep LISTEN | grep :9008 | tr -s ' ' | cut -d ' ' -f 2)
+# only the last solution by default
+curl -s -X POST "http://localhost:9008" -d '{"message":"Hello"}'
+# every solution as a JSON line
+curl -s -N -D - -o - -X POST "http://localhost:9008" -H 'Accept: application/x-ndjson' -d '{"message":"Hello"}' | grep -iv "^date:"
+# every solution as a Server-Sent Event
+curl -s -N -D - -o - -X POST "http://localhost:9008" -H 'Accept: text/event-stream' -d '{"message":"Hello"}' | grep -iv "^date:"
+# errors are sent as an error event
+curl -s -N -X POST "http://localhost:9008" -H 'Accept: text/event-stream' -d '{"message":"fail"}'
+kill $PID2 > /dev/null 2>&1
+exit 0
@@ -0,0 +1,55 @@
+// package main holds the implementation of a runner that streams improving
+// solutions.
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func main() {
+	err := run.NewHTTPRunner(algorithm,
+		// listen on port 9008
+		run.SetAddr[input, option, output](":9008"),
+		// override the default logger
+		run.SetLogger[input, option, output](
+			log.New(os.Stdout, "[demo] - ", log.Lshortfile),
+		),
+	).Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Solutions int `json:"solutions" default:"3" usage:"Number of solutions."`
+}
+
+type output struct {
+	Message string `json:"message"`
+	Value   int    `json:"value"`
+}
+
+// algorithm sends a number of improving solutions. It fails after the first
+// solution if the message is "fail".
+func algorithm(
+	_ context.Context, input input, opts option, solutions chan<- output,
+) error {
+	for i := 0; i < opts.Solutions; i++ {
+		time.Sleep(100 * time.Millisecond)
+		solutions <- output{Message: input.Message, Value: opts.Solutions - i}
+		if input.Message == "fail" {
+			return errors.New("the algorithm failed on purpose")
+		}
+	}
+	return nil
+}
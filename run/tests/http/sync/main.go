@@ -39,7 +39,9 @@ type output struct {
 	Message string `json:"message"`
 }
 
-func algorithm(_ context.Context, input input, opts option) (schema.Output, error) {
+func algorithm(ctx context.Context, input input, opts option) (schema.Output, error) {
+	// the id of the request is exported to the custom run statistics.
+	run.Export(ctx, run.RequestID)
 	// sleep for the specified duration, 1s by default as defined via go tags
 	time.Sleep(opts.Duration)
 	return schema.NewOutput(opts, output{Message: input.Message + " World!"}), nil
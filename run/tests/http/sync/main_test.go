@@ -21,5 +21,11 @@ func TestGoldenBash(t *testing.T) {
 	golden.BashTest(t, "./bash", golden.BashConfig{
 		DisplayStdout: true,
 		DisplayStderr: true,
+		OutputProcessConfig: golden.OutputProcessConfig{
+			VolatileRegexReplacements: []golden.VolatileRegexReplacement{
+				// the durations of the run statistics vary among runs.
+				{Regex: `"duration":\s*\d+\.\d+(e-\d+)?`, Replacement: `"duration": 0.123`},
+			},
+		},
 	})
 }
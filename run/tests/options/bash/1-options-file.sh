@@ -0,0 +1,4 @@
+# Read the options from a YAML file.
+./main.exe \
+    -runner.input.path input.json \
+    -runner.options.path options.yaml
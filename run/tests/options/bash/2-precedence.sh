@@ -0,0 +1,7 @@
+# The options file takes precedence over flags, which take precedence over
+# environment variables. The other fields keep their values.
+REPEAT=3 SEPARATOR=/ ./main.exe \
+    -runner.input.path input.json \
+    -runner.options.path options.json \
+    -greeting.prefix Howdy \
+    -timeout 2s
@@ -0,0 +1,4 @@
+# Attach an auxiliary input that the algorithm reads from the context.
+./main.exe \
+    -runner.input.path input.json \
+    -runner.input.auxiliary suffixes=suffixes.csv
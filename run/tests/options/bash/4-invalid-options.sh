@@ -0,0 +1,8 @@
+# Invalid options in the options file are option errors.
+echo '{"repeat": "many"}' > invalid.json
+./main.exe \
+    -runner.input.path input.json \
+    -runner.options.path invalid.json 2> stderr.txt
+echo "exit code: $?"
+sed -E 's/^[0-9/]+ [0-9:]+ //' stderr.txt
+rm invalid.json stderr.txt
@@ -0,0 +1,74 @@
+// package main holds the implementation of a runner that reads its options
+// from a file and an auxiliary input.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nextmv-io/sdk/run"
+)
+
+func main() {
+	err := run.CLI(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Words []string `json:"words"`
+}
+
+type option struct {
+	Separator string        `json:"separator" default:" " usage:"Separator of the words."`
+	Repeat    int           `json:"repeat" default:"1" usage:"Number of repetitions."`
+	Timeout   time.Duration `json:"timeout" default:"1s" usage:"Timeout of the algorithm."`
+	Greeting  struct {
+		Prefix string `json:"prefix" default:"Hi" usage:"Prefix of the greeting."`
+	} `json:"greeting"`
+}
+
+type output struct {
+	Message  string        `json:"message"`
+	Timeout  time.Duration `json:"timeout"`
+	Suffixes []string      `json:"suffixes,omitempty"`
+	// Sources are the sources of the options.
+	Sources map[string]run.OptionSource `json:"sources"`
+}
+
+type suffix struct {
+	Word   string `csv:"word"`
+	Suffix string `csv:"suffix"`
+}
+
+func algorithm(ctx context.Context, input input, opts option) (output, error) {
+	words := input.Words
+	var suffixes []string
+	for _, name := range run.AuxiliaryInputs(ctx) {
+		if name != "suffixes" {
+			continue
+		}
+		rows, err := run.DecodeAuxiliaryInput[[]suffix](ctx, name)
+		if err != nil {
+			return output{}, err
+		}
+		for _, row := range rows {
+			suffixes = append(suffixes, row.Word+row.Suffix)
+		}
+	}
+	var parts []string
+	for i := 0; i < opts.Repeat; i++ {
+		parts = append(parts, words...)
+	}
+	return output{
+		Message:  opts.Greeting.Prefix + ": " + strings.Join(parts, opts.Separator),
+		Timeout:  opts.Timeout,
+		Suffixes: suffixes,
+		Sources:  run.OptionSources(ctx),
+	}, nil
+}
@@ -0,0 +1,17 @@
+DIR=$(mktemp -d)
+echo '{"message": "Hello"}' | go run main.go \
+    -runner.profile.cpu $DIR/cpu.pprof \
+    -runner.profile.memory $DIR/heap.pprof \
+    -runner.profile.allocs $DIR/allocs.pprof \
+    -runner.profile.block $DIR/block.pprof \
+    -runner.profile.mutex $DIR/mutex.pprof \
+    -runner.profile.trace $DIR/trace.out | jq .solutions
+for f in allocs.pprof block.pprof cpu.pprof heap.pprof mutex.pprof trace.out; do
+    if [ -s $DIR/$f ]; then
+        echo "$f written"
+    fi
+done
+if go tool pprof -top -sample_index=alloc_space $DIR/allocs.pprof 2> /dev/null | grep -q main.allocate; then
+    echo "allocations of the algorithm profiled"
+fi
+rm -rf $DIR
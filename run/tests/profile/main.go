@@ -0,0 +1,64 @@
+// package main holds the implementation of a runner example that is profiled.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.CLI(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct {
+	Workers int `json:"workers" default:"4" usage:"Number of workers."`
+}
+
+type output struct {
+	Message string `json:"message"`
+	Sum     int    `json:"sum"`
+}
+
+func algorithm(_ context.Context, input input, opts option) (schema.Output, error) {
+	// the workers contend for a mutex, so that there is something to see in
+	// the block and mutex profiles.
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sum := 0
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				buf := allocate()
+				mutex.Lock()
+				sum += len(buf)
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return schema.NewOutput(opts, output{Message: input.Message + " World!", Sum: sum}), nil
+}
+
+// allocate allocates memory that shows up in the allocation profile.
+func allocate() []byte {
+	buf := make([]byte, 1<<20)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	return buf
+}
@@ -0,0 +1,4 @@
+echo '{"message": 1}' | ./main.exe -duration 0s 2> /dev/null
+echo "exit code: $?"
+echo '{"message": "Hello"}' | ./main.exe -duration 0s > /dev/null 2>&1
+echo "exit code: $?"
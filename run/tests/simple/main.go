@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/nextmv-io/sdk/run"
@@ -13,7 +14,10 @@ import (
 func main() {
 	err := run.CLI(algorithm).Run(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		// exit with a distinct code per kind of failure, e.g. 3 for invalid
+		// input.
+		os.Exit(run.ExitCode(err))
 	}
 }
 
@@ -29,6 +29,8 @@ func TestGolden(t *testing.T) {
 				{Key: ".solutions[0].statistics.time.elapsed", Replacement: golden.StableDuration},
 				{Key: ".solutions[0].statistics.time.elapsed_seconds", Replacement: golden.StableFloat},
 				{Key: ".solutions[0].statistics.time.start", Replacement: golden.StableTime},
+				{Key: ".statistics.run.duration", Replacement: golden.StableFloat},
+				{Key: ".statistics.result.duration", Replacement: golden.StableFloat},
 			},
 		},
 	)
@@ -41,5 +43,11 @@ func TestGoldenBash(t *testing.T) {
 	golden.BashTest(t, "./bash", golden.BashConfig{
 		DisplayStdout: true,
 		DisplayStderr: true,
+		OutputProcessConfig: golden.OutputProcessConfig{
+			VolatileRegexReplacements: []golden.VolatileRegexReplacement{
+				// the durations of the run statistics vary among runs.
+				{Regex: `"duration":\s*\d+\.\d+(e-\d+)?`, Replacement: `"duration": 0.123`},
+			},
+		},
 	})
 }
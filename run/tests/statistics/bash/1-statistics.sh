@@ -0,0 +1,3 @@
+# The run duration and iterations, the time to the solution, the value series
+# of all solutions and the custom series are collected automatically.
+echo '{"costs": [30, 20, 10]}' | ./main.exe | jq -c '{solutions, statistics}'
@@ -0,0 +1,49 @@
+// package main holds the implementation of a runner whose output statistics
+// are collected automatically.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.NewCLIRunner(algorithm).Run(context.Background())
+	if err != nil {
+		log.Println(err)
+		os.Exit(run.ExitCode(err))
+	}
+}
+
+type input struct {
+	Costs []float64 `json:"costs"`
+}
+
+type option struct{}
+
+type solution struct {
+	Cost float64 `json:"cost"`
+}
+
+// Value returns the cost of the solution, which makes it a run.Valuer.
+func (s solution) Value() float64 {
+	return s.Cost
+}
+
+func algorithm(
+	ctx context.Context, input input, opts option, solutions chan<- schema.Output,
+) error {
+	// the number of costs is exported to the custom run statistics.
+	run.Set(ctx, "costs", len(input.Costs))
+	run.Export(ctx, "costs")
+	for i, cost := range input.Costs {
+		run.AddIterations(ctx, 10)
+		run.RecordSeriesValue(ctx, "temperature", float64(100-10*i))
+		solutions <- schema.NewOutput(opts, solution{Cost: cost})
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// package main holds the implementation of a runner example that is stopped
+// by a time limit.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/schema"
+)
+
+func main() {
+	err := run.NewCLIRunner(algorithm).Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type input struct {
+	Message string `json:"message" usage:"Message to print."`
+}
+
+type option struct{}
+
+type output struct {
+	Message  string `json:"message"`
+	Improved bool   `json:"improved"`
+}
+
+func algorithm(
+	ctx context.Context,
+	input input,
+	opts option,
+	solutions chan<- schema.Output,
+) error {
+	// send a first solution right away
+	solutions <- schema.NewOutput(opts, output{Message: input.Message})
+	// keep searching until the runner stops us, then report the best solution
+	<-ctx.Done()
+	solutions <- schema.NewOutput(
+		opts,
+		output{Message: input.Message + " World!", Improved: true},
+	)
+	return ctx.Err()
+}
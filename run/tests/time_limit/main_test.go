@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nextmv-io/sdk/golden"
+)
+
+func TestMain(m *testing.M) {
+	golden.Setup()
+	code := m.Run()
+	golden.Teardown()
+	os.Exit(code)
+}
+
+// TestGolden executes a golden file test, where the .json input is fed and an
+// output is expected. The algorithm only returns once the time limit is
+// reached.
+func TestGolden(t *testing.T) {
+	golden.FileTests(
+		t,
+		"input.json",
+		golden.Config{
+			Args: []string{
+				"-runner.duration=100ms",
+			},
+			TransientFields: []golden.TransientField{
+				{Key: ".version.sdk", Replacement: golden.StableVersion},
+				{Key: ".statistics.run.duration", Replacement: golden.StableFloat},
+				{Key: ".statistics.result.duration", Replacement: golden.StableFloat},
+			},
+		},
+	)
+}
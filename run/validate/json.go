@@ -2,12 +2,10 @@
 package validate
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"reflect"
 	"strings"
 
@@ -40,7 +38,7 @@ func (j JSONValidator[Input]) Validate(_ context.Context, input any) (retErr err
 		// generate schema for input struct
 		s, err := humaSchema.Generate(reflect.TypeOf(new(Input)))
 		if err != nil {
-			log.Fatal(err)
+			return &SchemaError{Err: fmt.Errorf("generate schema of the input: %w", err)}
 		}
 		if s.Properties == nil {
 			s.Properties = map[string]*humaSchema.Schema{}
@@ -51,7 +49,7 @@ func (j JSONValidator[Input]) Validate(_ context.Context, input any) (retErr err
 		// serialize s to json
 		schema, err := json.Marshal(s)
 		if err != nil {
-			log.Fatal(err)
+			return &SchemaError{Err: fmt.Errorf("marshal schema of the input: %w", err)}
 		}
 		j.schema = schema
 	}
@@ -63,25 +61,88 @@ func (j JSONValidator[Input]) Validate(_ context.Context, input any) (retErr err
 		return fmt.Errorf("input is not an io.Reader")
 	}
 
-	var buf bytes.Buffer
-	_, err := buf.ReadFrom(reader)
-	if err != nil {
+	// decode the input while it is read, so that it is not copied first.
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
 		return err
 	}
 
-	loader := gojsonschema.NewBytesLoader(buf.Bytes())
+	loader := gojsonschema.NewRawLoader(document)
 
 	result, err := gojsonschema.Validate(schemaLoader, loader)
 	if err != nil {
-		return err
+		return &SchemaError{Err: err}
 	}
 
 	if !result.Valid() {
-		sb := strings.Builder{}
+		validationErr := &Error{}
 		for _, desc := range result.Errors() {
-			sb.WriteString(desc.String() + "\n")
+			validationErr.Details = append(validationErr.Details, Detail{
+				Location: pointer(desc),
+				Message:  desc.String(),
+			})
 		}
-		return fmt.Errorf(sb.String())
+		return validationErr
 	}
 	return nil
 }
+
+// Error is returned by the JSONValidator if the input does not match the
+// schema. It holds one detail per violation.
+type Error struct {
+	Details []Detail
+}
+
+// SchemaError is returned by the JSONValidator if the schema cannot be
+// generated or loaded. Unlike Error, it is not caused by the input.
+type SchemaError struct {
+	Err error
+}
+
+func (e *SchemaError) Error() string {
+	return "schema: " + e.Err.Error()
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// Detail is a single schema violation. The location is a JSON pointer to the
+// offending value, e.g. /stops/0/id.
+type Detail struct {
+	Location string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	sb := strings.Builder{}
+	for _, detail := range e.Details {
+		sb.WriteString(detail.Message + "\n")
+	}
+	return sb.String()
+}
+
+// pointer returns the JSON pointer of the value a result error refers to. For
+// missing properties, it points to the property itself. The pointer of the
+// whole input is empty.
+func pointer(desc gojsonschema.ResultError) string {
+	// the names are joined with a delimiter that JSON names hardly contain, so
+	// that names with a slash are not split.
+	const delimiter = "\x00"
+	names := strings.Split(desc.Context().String(delimiter), delimiter)[1:]
+	if desc.Type() == "required" {
+		if property, ok := desc.Details()["property"].(string); ok {
+			names = append(names, property)
+		}
+	}
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString("/" + pointerEscaper.Replace(name))
+	}
+	return sb.String()
+}
+
+// pointerEscaper escapes the names of a JSON pointer, see RFC 6901.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
@@ -0,0 +1,42 @@
+package validate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nextmv-io/sdk/run/validate"
+)
+
+func TestJSONValidatorLocations(t *testing.T) {
+	type input struct {
+		Name   string         `json:"a/b~c" required:"true"`
+		Values map[string]int `json:"values,omitempty"`
+	}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`[]`, []string{""}},
+		{`{}`, []string{"/a~1b~0c"}},
+		{`{"a/b~c": "x", "values": {"x/y": "1"}}`, []string{"/values/x~1y"}},
+	}
+	validator := validate.JSON[input](nil)
+	for _, test := range tests {
+		err := validator(context.Background(), strings.NewReader(test.input))
+		var validationErr *validate.Error
+		if !errors.As(err, &validationErr) {
+			t.Errorf("got %v; want %T", err, validationErr)
+			continue
+		}
+		var got []string
+		for _, detail := range validationErr.Details {
+			got = append(got, detail.Location)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %q; want %q", got, test.want)
+		}
+	}
+}